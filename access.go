@@ -0,0 +1,392 @@
+package gogns3
+
+import (
+	"encoding/json"
+)
+
+// FeatureAccessControl is the management of users, groups, roles and access
+// control entries, introduced by the v3 API
+var FeatureAccessControl = Feature{Name: "access control", MinVersion: "3.0.0"}
+
+// User is a user account of a GNS3 server. A user must be active to log in.
+// The password is only sent to the server, it is never read back.
+type User struct {
+	Email        string  `json:"email,omitempty"`
+	FullName     string  `json:"full_name,omitempty"`
+	IsActive     bool    `json:"is_active"`
+	IsSuperadmin bool    `json:"is_superadmin,omitempty"`
+	LastLogin    string  `json:"last_login,omitempty"`
+	Password     string  `json:"password,omitempty"`
+	Server       *Server `json:"-"`
+	UUID         string  `json:"user_id,omitempty"`
+	Username     string  `json:"username"`
+}
+
+// Group is a group of users of a GNS3 server
+type Group struct {
+	IsBuiltin bool    `json:"is_builtin,omitempty"`
+	Name      string  `json:"name"`
+	Server    *Server `json:"-"`
+	UUID      string  `json:"user_group_id,omitempty"`
+}
+
+// Role is a set of privileges of a GNS3 server
+type Role struct {
+	Description string  `json:"description,omitempty"`
+	IsBuiltin   bool    `json:"is_builtin,omitempty"`
+	Name        string  `json:"name"`
+	Server      *Server `json:"-"`
+	UUID        string  `json:"role_id,omitempty"`
+}
+
+// ACE is an access control entry of a GNS3 server: it grants (or denies) the
+// privileges of a role to a user or a group on a resource path, e.g.
+// /projects/<project_id>
+type ACE struct {
+	ACEType   string  `json:"ace_type"`
+	Allowed   bool    `json:"allowed"`
+	GroupID   string  `json:"group_id,omitempty"`
+	Path      string  `json:"path"`
+	Propagate bool    `json:"propagate"`
+	RoleID    string  `json:"role_id"`
+	Server    *Server `json:"-"`
+	UserID    string  `json:"user_id,omitempty"`
+	UUID      string  `json:"ace_id,omitempty"`
+}
+
+// Types of access control entries
+const (
+	ACETypeGroup = "group"
+	ACETypeUser  = "user"
+)
+
+// accessRequest sends a request to the access control API of the server. The
+// request is JSON-encoded from in (if not nil) and the answer decoded into out
+// (if not nil).
+func (s *Server) accessRequest(method string, url string, in interface{}, out interface{}) error {
+	if err := s.require(FeatureAccessControl); err != nil {
+		return err
+	}
+	var b []byte
+	if in != nil {
+		b, _ = json.Marshal(in)
+	}
+	status, content, err := s.HTTPRequest(method, url, b)
+	if err != nil {
+		return err
+	}
+	if !(status >= 200 && status < 300) {
+		serverError := ServerError{Status: status}
+		json.Unmarshal(content, &serverError)
+		return &serverError
+	}
+	if out != nil {
+		json.Unmarshal(content, out)
+	}
+	return nil
+}
+
+// accessURL returns the URL of the given path of the access control API
+func (s *Server) accessURL(elements ...string) string {
+	return s.endpointURL(nil, append([]string{"access"}, elements...)...)
+}
+
+// GetUsers gets the list of all users of the server
+func (s *Server) GetUsers() ([]User, error) {
+	users := []User{}
+	if err := s.accessRequest("GET", s.accessURL("users"), nil, &users); err != nil {
+		return nil, err
+	}
+	for idx := range users {
+		users[idx].Server = s
+	}
+	return users, nil
+}
+
+func (u *User) url() string {
+	return u.Server.accessURL("users", u.UUID)
+}
+
+// Read reads an existing user on the server
+func (u *User) Read() error {
+	users, err := u.Server.GetUsers()
+	if err != nil {
+		return err
+	}
+	for _, user := range users {
+		if user.Username == u.Username {
+			*u = user
+			return nil
+		}
+	}
+	return &ServerError{Status: 404, Message: "User does not exist on server"}
+}
+
+// Exists checks a user exists on the server
+func (u *User) Exists() (bool, error) {
+	// Use a new struct because Read() will overwrite it
+	user := User{
+		Server:   u.Server,
+		Username: u.Username,
+	}
+
+	err := user.Read()
+	return err == nil, err
+}
+
+// Create creates a user on the server. The password is cleared once sent.
+func (u *User) Create() error {
+	if err := u.Server.accessRequest("POST", u.Server.accessURL("users"), u, u); err != nil {
+		return err
+	}
+	u.Password = ""
+	return nil
+}
+
+// Delete deletes a user on the server
+// Read() may be called before a Delete() can be executed
+func (u *User) Delete() error {
+	if u.UUID == "" {
+		if err := u.Read(); err != nil {
+			return err
+		}
+	}
+	return u.Server.accessRequest("DELETE", u.url(), nil, nil)
+}
+
+// Update updates a user on the server. The password is only changed if set,
+// and cleared once sent.
+func (u *User) Update() error {
+	if err := u.Server.accessRequest("PUT", u.url(), u, u); err != nil {
+		return err
+	}
+	u.Password = ""
+	return nil
+}
+
+// GetGroups gets the list of all user groups of the server
+func (s *Server) GetGroups() ([]Group, error) {
+	groups := []Group{}
+	if err := s.accessRequest("GET", s.accessURL("groups"), nil, &groups); err != nil {
+		return nil, err
+	}
+	for idx := range groups {
+		groups[idx].Server = s
+	}
+	return groups, nil
+}
+
+func (g *Group) url(elements ...string) string {
+	return g.Server.accessURL(append([]string{"groups", g.UUID}, elements...)...)
+}
+
+// Read reads an existing group on the server
+func (g *Group) Read() error {
+	groups, err := g.Server.GetGroups()
+	if err != nil {
+		return err
+	}
+	for _, group := range groups {
+		if group.Name == g.Name {
+			*g = group
+			return nil
+		}
+	}
+	return &ServerError{Status: 404, Message: "Group does not exist on server"}
+}
+
+// Exists checks a group exists on the server
+func (g *Group) Exists() (bool, error) {
+	// Use a new struct because Read() will overwrite it
+	group := Group{
+		Name:   g.Name,
+		Server: g.Server,
+	}
+
+	err := group.Read()
+	return err == nil, err
+}
+
+// Create creates a group on the server
+func (g *Group) Create() error {
+	return g.Server.accessRequest("POST", g.Server.accessURL("groups"), g, g)
+}
+
+// Delete deletes a group on the server
+// Read() may be called before a Delete() can be executed
+func (g *Group) Delete() error {
+	if g.UUID == "" {
+		if err := g.Read(); err != nil {
+			return err
+		}
+	}
+	return g.Server.accessRequest("DELETE", g.url(), nil, nil)
+}
+
+// Update updates a group on the server
+func (g *Group) Update() error {
+	return g.Server.accessRequest("PUT", g.url(), g, g)
+}
+
+// GetMembers gets the list of the users of the group
+func (g *Group) GetMembers() ([]User, error) {
+	users := []User{}
+	if err := g.Server.accessRequest("GET", g.url("members"), nil, &users); err != nil {
+		return nil, err
+	}
+	for idx := range users {
+		users[idx].Server = g.Server
+	}
+	return users, nil
+}
+
+// AddMember adds a user to the group
+func (g *Group) AddMember(u *User) error {
+	return g.Server.accessRequest("PUT", g.url("members", u.UUID), nil, nil)
+}
+
+// RemoveMember removes a user from the group
+func (g *Group) RemoveMember(u *User) error {
+	return g.Server.accessRequest("DELETE", g.url("members", u.UUID), nil, nil)
+}
+
+// GetRoles gets the list of all roles of the server
+func (s *Server) GetRoles() ([]Role, error) {
+	roles := []Role{}
+	if err := s.accessRequest("GET", s.accessURL("roles"), nil, &roles); err != nil {
+		return nil, err
+	}
+	for idx := range roles {
+		roles[idx].Server = s
+	}
+	return roles, nil
+}
+
+func (r *Role) url() string {
+	return r.Server.accessURL("roles", r.UUID)
+}
+
+// Read reads an existing role on the server
+func (r *Role) Read() error {
+	roles, err := r.Server.GetRoles()
+	if err != nil {
+		return err
+	}
+	for _, role := range roles {
+		if role.Name == r.Name {
+			*r = role
+			return nil
+		}
+	}
+	return &ServerError{Status: 404, Message: "Role does not exist on server"}
+}
+
+// Exists checks a role exists on the server
+func (r *Role) Exists() (bool, error) {
+	// Use a new struct because Read() will overwrite it
+	role := Role{
+		Name:   r.Name,
+		Server: r.Server,
+	}
+
+	err := role.Read()
+	return err == nil, err
+}
+
+// Create creates a role on the server
+func (r *Role) Create() error {
+	return r.Server.accessRequest("POST", r.Server.accessURL("roles"), r, r)
+}
+
+// Delete deletes a role on the server
+// Read() may be called before a Delete() can be executed
+func (r *Role) Delete() error {
+	if r.UUID == "" {
+		if err := r.Read(); err != nil {
+			return err
+		}
+	}
+	return r.Server.accessRequest("DELETE", r.url(), nil, nil)
+}
+
+// Update updates a role on the server
+func (r *Role) Update() error {
+	return r.Server.accessRequest("PUT", r.url(), r, r)
+}
+
+// GetACEs gets the list of all access control entries of the server
+func (s *Server) GetACEs() ([]ACE, error) {
+	aces := []ACE{}
+	if err := s.accessRequest("GET", s.accessURL("acl"), nil, &aces); err != nil {
+		return nil, err
+	}
+	for idx := range aces {
+		aces[idx].Server = s
+	}
+	return aces, nil
+}
+
+func (a *ACE) url() string {
+	return a.Server.accessURL("acl", a.UUID)
+}
+
+// Read reads an existing access control entry on the server
+func (a *ACE) Read() error {
+	aces, err := a.Server.GetACEs()
+	if err != nil {
+		return err
+	}
+	for _, ace := range aces {
+		// ACEs are not named, so read will be based on UUID and not name
+		if ace.UUID == a.UUID {
+			*a = ace
+			return nil
+		}
+	}
+	return &ServerError{Status: 404, Message: "ACE does not exist on server"}
+}
+
+// Exists checks an access control entry exists on the server
+func (a *ACE) Exists() (bool, error) {
+	// Use a new struct because Read() will overwrite it
+	ace := ACE{
+		Server: a.Server,
+		UUID:   a.UUID,
+	}
+
+	err := ace.Read()
+	return err == nil, err
+}
+
+// Create creates an access control entry on the server
+func (a *ACE) Create() error {
+	return a.Server.accessRequest("POST", a.Server.accessURL("acl"), a, a)
+}
+
+// Delete deletes an access control entry on the server
+func (a *ACE) Delete() error {
+	return a.Server.accessRequest("DELETE", a.url(), nil, nil)
+}
+
+// Update updates an access control entry on the server
+func (a *ACE) Update() error {
+	return a.Server.accessRequest("PUT", a.url(), a, a)
+}
+
+// Grant grants the privileges of a role on the project, and on its nodes,
+// links and drawings, to a user
+func (p *Project) Grant(u *User, r *Role) (*ACE, error) {
+	ace := &ACE{
+		ACEType:   ACETypeUser,
+		Allowed:   true,
+		Path:      "/projects/" + p.UUID,
+		Propagate: true,
+		RoleID:    r.UUID,
+		Server:    p.Server,
+		UserID:    u.UUID,
+	}
+	if err := ace.Create(); err != nil {
+		return nil, err
+	}
+	return ace, nil
+}
@@ -0,0 +1,171 @@
+package gogns3
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+// newTestAccessServer starts a fake v3 server keeping the created access
+// control objects in memory
+func newTestAccessServer() (*Server, func()) {
+	objects := map[string]map[string]map[string]interface{}{
+		"users": {}, "groups": {}, "roles": {}, "acl": {},
+	}
+	ids := map[string]string{"users": "user_id", "groups": "user_group_id", "roles": "role_id", "acl": "ace_id"}
+	members := map[string]bool{}
+	next := 0
+
+	return newTestHTTPServer(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/v3/version" {
+			w.Write([]byte(`{"local": false, "version": "3.0.0"}`))
+			return
+		}
+		elements := strings.Split(strings.TrimPrefix(r.URL.Path, "/v3/access/"), "/")
+		kind := elements[0]
+		if objects[kind] == nil {
+			http.NotFound(w, r)
+			return
+		}
+
+		switch {
+		case len(elements) == 1 && r.Method == "GET":
+			list := []map[string]interface{}{}
+			for _, o := range objects[kind] {
+				list = append(list, o)
+			}
+			json.NewEncoder(w).Encode(list)
+		case len(elements) == 1 && r.Method == "POST":
+			o := map[string]interface{}{}
+			json.NewDecoder(r.Body).Decode(&o)
+			delete(o, "password")
+			next++
+			o[ids[kind]] = strconv.Itoa(next)
+			objects[kind][o[ids[kind]].(string)] = o
+			w.WriteHeader(http.StatusCreated)
+			json.NewEncoder(w).Encode(o)
+		case len(elements) == 2 && r.Method == "PUT":
+			o := map[string]interface{}{}
+			json.NewDecoder(r.Body).Decode(&o)
+			delete(o, "password")
+			objects[kind][elements[1]] = o
+			json.NewEncoder(w).Encode(o)
+		case len(elements) == 2 && r.Method == "DELETE":
+			delete(objects[kind], elements[1])
+			w.WriteHeader(http.StatusNoContent)
+		case len(elements) == 4 && kind == "groups" && r.Method == "PUT":
+			members[elements[1]+"/"+elements[3]] = true
+			w.WriteHeader(http.StatusNoContent)
+		case len(elements) == 3 && kind == "groups" && r.Method == "GET":
+			list := []map[string]interface{}{}
+			for member := range members {
+				if strings.HasPrefix(member, elements[1]+"/") {
+					list = append(list, objects["users"][strings.TrimPrefix(member, elements[1]+"/")])
+				}
+			}
+			json.NewEncoder(w).Encode(list)
+		default:
+			http.NotFound(w, r)
+		}
+	})
+}
+
+func TestUserCreateUpdateDelete(t *testing.T) {
+	s, stop := newTestAccessServer()
+	defer stop()
+
+	u := User{Server: s, Username: "student1", Password: "secret", IsActive: true}
+	if err := u.Create(); err != nil {
+		t.Fatal(err)
+	}
+	if u.UUID == "" || !u.IsActive || u.Password != "" {
+		t.Errorf("This user seems to be misconfigured (%+v)", u)
+	}
+
+	u.FullName = "Student One"
+	if err := u.Update(); err != nil {
+		t.Fatal(err)
+	}
+	user := User{Server: s, Username: "student1"}
+	if err := user.Read(); err != nil || user.FullName != "Student One" {
+		t.Errorf("This user seems to be misupdated (%+v, %v)", user, err)
+	}
+
+	user.UUID = ""
+	if err := user.Delete(); err != nil {
+		t.Fatal(err)
+	}
+	if b, _ := u.Exists(); b {
+		t.Error("This user must have been deleted")
+	}
+}
+
+func TestGroupMembers(t *testing.T) {
+	s, stop := newTestAccessServer()
+	defer stop()
+
+	u := User{Server: s, Username: "student1", IsActive: true}
+	g := Group{Server: s, Name: "students"}
+	if err := u.Create(); err != nil {
+		t.Fatal(err)
+	}
+	if err := g.Create(); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := g.AddMember(&u); err != nil {
+		t.Fatal(err)
+	}
+	members, err := g.GetMembers()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(members) != 1 || members[0].Username != "student1" || members[0].Server != s {
+		t.Errorf("These members seem to be misread (%+v)", members)
+	}
+}
+
+func TestProjectGrant(t *testing.T) {
+	s, stop := newTestAccessServer()
+	defer stop()
+
+	u := User{Server: s, Username: "student1", IsActive: true}
+	r := Role{Server: s, Name: "Student"}
+	u.Create()
+	r.Create()
+
+	p := Project{Name: "student1", Server: s, UUID: "0a1b2c3d"}
+	ace, err := p.Grant(&u, &r)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	entry := ACE{Server: s, UUID: ace.UUID}
+	if err := entry.Read(); err != nil {
+		t.Fatal(err)
+	}
+	expected := ACE{ACEType: ACETypeUser, Allowed: true, Path: "/projects/0a1b2c3d", Propagate: true, RoleID: r.UUID, Server: s, UserID: u.UUID, UUID: ace.UUID}
+	if entry != expected {
+		t.Errorf("This ACE seems to be misconfigured (%+v)", entry)
+	}
+}
+
+func TestAccessControlUnsupported(t *testing.T) {
+	s, stop := newTestHTTPServer(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/v2/version" {
+			w.Write([]byte(`{"local": true, "version": "2.2.17"}`))
+			return
+		}
+		http.NotFound(w, r)
+	})
+	defer stop()
+
+	_, err := s.GetUsers()
+	var unsupported *UnsupportedError
+	if !errors.As(err, &unsupported) || unsupported.Feature != FeatureAccessControl {
+		t.Errorf("Access control must be unsupported by server 2.2 (%v)", err)
+	}
+}
@@ -0,0 +1,272 @@
+package gogns3
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"net"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Versions of the GNS3 server API
+const (
+	APIv2 = 2
+	APIv3 = 3
+)
+
+// tokenRefreshMargin is the time before the expiry of a token at which it is
+// refreshed
+const tokenRefreshMargin = time.Minute
+
+// v3Endpoints are the endpoints renamed by the v3 API, relative to the object
+// they apply to
+var v3Endpoints = map[string]string{
+	"pcap":          "capture/stream",
+	"start_capture": "capture/start",
+	"stop_capture":  "capture/stop",
+}
+
+// baseURL returns the URL of the server, without the API path. An IPv6 host
+// is enclosed in brackets.
+func (s *Server) baseURL() string {
+	scheme := s.Scheme
+	if scheme == "" {
+		scheme = DefaultScheme
+	}
+	return scheme + "://" + net.JoinHostPort(s.Host, strconv.Itoa(s.Port))
+}
+
+// apiURL returns the base URL of the server API
+func (s *Server) apiURL() string {
+	return s.baseURL() + "/v" + strconv.Itoa(s.API())
+}
+
+// endpointURL returns the URL of an endpoint of the server API, given the
+// elements of its path and its query parameters, if any. The elements are
+// escaped, except their slashes which separate the segments of the path.
+func (s *Server) endpointURL(query url.Values, elements ...string) string {
+	segments := []string{}
+	for _, element := range elements {
+		for _, segment := range strings.Split(strings.Trim(element, "/"), "/") {
+			segments = append(segments, url.PathEscape(segment))
+		}
+	}
+	u := s.apiURL() + "/" + strings.Join(segments, "/")
+	if len(query) != 0 {
+		u += "?" + query.Encode()
+	}
+	return u
+}
+
+// API returns the version of the API used to talk to the server. Unless it is
+// forced with APIVersion, the version is negotiated on the first call: v3 if
+// the server answers on the v3 API, v2 if it does not know this API. Until the
+// negotiation succeeds, e.g. while the server cannot be reached or answers an
+// error, v2 is assumed and the negotiation is tried again on the next call.
+func (s *Server) API() int {
+	apiVersion, _ := s.negotiateAPI()
+	return apiVersion
+}
+
+// negotiateAPI returns the version of the API used to talk to the server,
+// negotiating it if needed. On error, v2 is returned and nothing is cached.
+func (s *Server) negotiateAPI() (int, error) {
+	if s.APIVersion != 0 {
+		return s.APIVersion, nil
+	}
+	if apiVersion := s.negotiatedAPI(); apiVersion != 0 {
+		return apiVersion, nil
+	}
+
+	// one negotiation at a time, the concurrent callers wait for its result
+	s.negotiateMu.Lock()
+	defer s.negotiateMu.Unlock()
+	apiVersion := s.negotiatedAPI()
+	if apiVersion != 0 {
+		return apiVersion, nil
+	}
+
+	// the version endpoint does not require authentication
+	release, err := s.acquire(context.Background())
+	if err != nil {
+		return APIv2, err
+	}
+	defer release()
+	ctx, cancel := context.WithTimeout(context.Background(), s.timeout())
+	defer cancel()
+	req, _ := http.NewRequestWithContext(ctx, "GET", s.baseURL()+"/v3/version", nil)
+	resp, err := s.client().Do(req)
+	if err != nil {
+		return APIv2, err
+	}
+	defer resp.Body.Close()
+	v := &Version{}
+	switch resp.StatusCode {
+	case http.StatusNotFound:
+		// a v2 server does not know the v3 API
+		apiVersion = APIv2
+	case http.StatusOK:
+		if err := json.NewDecoder(resp.Body).Decode(v); err != nil {
+			return APIv2, err
+		}
+		apiVersion = APIv2
+		if compareVersions(v.Version, "3.0.0") >= 0 {
+			apiVersion = APIv3
+		}
+	default:
+		serverError := ServerError{Status: resp.StatusCode}
+		json.NewDecoder(resp.Body).Decode(&serverError)
+		return APIv2, &serverError
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.apiVersion = apiVersion
+	if apiVersion == APIv3 && s.version == nil {
+		s.version = v
+	}
+	return apiVersion, nil
+}
+
+// negotiatedAPI returns the negotiated version of the API, or 0 if it is not
+// negotiated yet
+func (s *Server) negotiatedAPI() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.apiVersion
+}
+
+// endpoint returns the name of an endpoint in the API used by the server,
+// given its v2 name
+func (s *Server) endpoint(name string) string {
+	if s.API() == APIv3 && v3Endpoints[name] != "" {
+		return v3Endpoints[name]
+	}
+	return name
+}
+
+// Login authenticates a user on a v3 server. The access token is then sent
+// with every request, and refreshed with the same credentials when it expires.
+// On a v2 server, the credentials are used for HTTP basic authentication. An
+// error is returned if the API version cannot be negotiated.
+func (s *Server) Login(user string, password string) error {
+	s.mu.Lock()
+	s.User, s.Password = user, password
+	s.mu.Unlock()
+	apiVersion, err := s.negotiateAPI()
+	if err != nil {
+		return err
+	}
+	if apiVersion != APIv3 {
+		return nil
+	}
+
+	s.loginMu.Lock()
+	defer s.loginMu.Unlock()
+	return s.login()
+}
+
+// accessToken returns the access token of the server, if logged in
+func (s *Server) accessToken() string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.token
+}
+
+// login requests an access token for the user of the server. The caller must
+// hold loginMu, so that concurrent requests needing a token log in only once.
+func (s *Server) login() error {
+	user, password := s.credentials()
+	b, _ := json.Marshal(struct {
+		Password string `json:"password"`
+		Username string `json:"username"`
+	}{password, user})
+
+	// the URL is built first, as it may negotiate the API version
+	u := s.endpointURL(nil, "access", "users", "authenticate")
+	release, err := s.acquire(context.Background())
+	if err != nil {
+		return err
+	}
+	defer release()
+	ctx, cancel := context.WithTimeout(context.Background(), s.timeout())
+	defer cancel()
+	req, _ := http.NewRequestWithContext(ctx, "POST", u, bytes.NewReader(b))
+	req.Header.Add("Content-Type", "application/json")
+	resp, err := s.client().Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		serverError := ServerError{Status: resp.StatusCode}
+		json.NewDecoder(resp.Body).Decode(&serverError)
+		return &serverError
+	}
+
+	token := struct {
+		AccessToken string `json:"access_token"`
+	}{}
+	if err := json.NewDecoder(resp.Body).Decode(&token); err != nil {
+		return err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.token = token.AccessToken
+	s.tokenExpiry = tokenExpiry(token.AccessToken)
+	return nil
+}
+
+// refreshToken logs in again when the access token is missing or about to
+// expire. It does nothing without credentials or on a v2 server.
+func (s *Server) refreshToken() error {
+	if user, _ := s.credentials(); user == "" || s.API() != APIv3 {
+		return nil
+	}
+
+	s.loginMu.Lock()
+	defer s.loginMu.Unlock()
+	s.mu.Lock()
+	valid := s.token != "" && (s.tokenExpiry.IsZero() || time.Until(s.tokenExpiry) > tokenRefreshMargin)
+	s.mu.Unlock()
+	if valid {
+		return nil
+	}
+	return s.login()
+}
+
+// relogin logs in again after the given access token has been rejected,
+// unless another request already did it
+func (s *Server) relogin(rejected string) error {
+	s.loginMu.Lock()
+	defer s.loginMu.Unlock()
+	if s.accessToken() != rejected {
+		return nil
+	}
+	return s.login()
+}
+
+// tokenExpiry returns the expiry of a JWT, or the zero time if it cannot be
+// read. The signature is not checked, only the server can do it.
+func tokenExpiry(token string) time.Time {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return time.Time{}
+	}
+	payload, err := base64.RawURLEncoding.DecodeString(parts[1])
+	if err != nil {
+		return time.Time{}
+	}
+	claims := struct {
+		Exp int64 `json:"exp"`
+	}{}
+	if json.Unmarshal(payload, &claims) != nil || claims.Exp == 0 {
+		return time.Time{}
+	}
+	return time.Unix(claims.Exp, 0)
+}
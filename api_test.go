@@ -0,0 +1,222 @@
+package gogns3
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// newTestToken returns an unsigned JWT expiring at the given time
+func newTestToken(id int, exp time.Time) string {
+	payload, _ := json.Marshal(map[string]interface{}{"sub": "admin", "exp": exp.Unix(), "id": id})
+	return "eyJhbGciOiJIUzI1NiIsInR5cCI6IkpXVCJ9." + base64.RawURLEncoding.EncodeToString(payload) + ".c2lnbmF0dXJl"
+}
+
+// newTestV3Server starts a fake v3 server accepting the admin/secret user. The
+// tokens are valid for the given duration, and only the last one is accepted.
+func newTestV3Server(validity time.Duration, paths *[]string) (*Server, func()) {
+	mu := sync.Mutex{}
+	logins := 0
+	// the tokens issued, which stay valid like on a real server
+	tokens := map[string]bool{}
+	return newTestHTTPServer(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		*paths = append(*paths, r.Method+" "+r.URL.Path)
+		switch r.URL.Path {
+		case "/v3/version":
+			w.Write([]byte(`{"controller_host": "127.0.0.1", "local": false, "version": "3.0.0"}`))
+			return
+		case "/v3/access/users/authenticate":
+			credentials := map[string]string{}
+			json.NewDecoder(r.Body).Decode(&credentials)
+			if credentials["username"] != "admin" || credentials["password"] != "secret" {
+				w.WriteHeader(http.StatusUnauthorized)
+				w.Write([]byte(`{"message": "Authentication was unsuccessful."}`))
+				return
+			}
+			logins++
+			token := newTestToken(logins, time.Now().Add(validity))
+			tokens[token] = true
+			fmt.Fprintf(w, `{"access_token": %q, "token_type": "bearer"}`, token)
+			return
+		}
+
+		if !tokens[strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer ")] {
+			w.WriteHeader(http.StatusUnauthorized)
+			w.Write([]byte(`{"message": "Could not validate credentials"}`))
+			return
+		}
+		switch r.URL.Path {
+		case "/v3/projects":
+			w.Write([]byte(`[{"name": "lab", "project_id": "1", "status": "opened"}]`))
+		case "/v3/projects/1/links/a/capture/start":
+			w.Write([]byte(`{"link_id": "a", "capturing": true}`))
+		default:
+			http.NotFound(w, r)
+		}
+	})
+}
+
+func TestServerV3Login(t *testing.T) {
+	paths := []string{}
+	s, stop := newTestV3Server(time.Hour, &paths)
+	defer stop()
+
+	if s.API() != APIv3 {
+		t.Fatal("The v3 API must be negotiated")
+	}
+	if err := s.Login("admin", "wrong"); err == nil {
+		t.Error("A wrong password must be rejected")
+	}
+	if err := s.Login("admin", "secret"); err != nil {
+		t.Fatal(err)
+	}
+
+	projects, err := s.GetProjects()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(projects) != 1 || projects[0].Name != "lab" {
+		t.Errorf("These projects seem to be misread (%+v)", projects)
+	}
+
+	l := Link{Project: &projects[0], UUID: "a"}
+	if err := l.StartCapture("DLT_EN10MB", ""); err != nil {
+		t.Fatal(err)
+	}
+	if !l.Capturing {
+		t.Error("This link must be capturing")
+	}
+
+	v, _ := s.Version()
+	if v.Version != "3.0.0" {
+		t.Errorf("This version seems to be misread (%+v)", v)
+	}
+}
+
+func TestServerV3TokenRefresh(t *testing.T) {
+	paths := []string{}
+	// the tokens expire within the refresh margin, each request logs in again
+	s, stop := newTestV3Server(time.Second, &paths)
+	defer stop()
+	s.User, s.Password = "admin", "secret"
+
+	for i := 0; i < 2; i++ {
+		if _, err := s.GetProjects(); err != nil {
+			t.Fatal(err)
+		}
+	}
+	logins := 0
+	for _, path := range paths {
+		if path == "POST /v3/access/users/authenticate" {
+			logins++
+		}
+	}
+	if logins != 2 {
+		t.Errorf("The token must be refreshed before each request (%v)", paths)
+	}
+
+	// a rejected token is refreshed and the request sent again
+	s.token, s.tokenExpiry = newTestToken(0, time.Now().Add(time.Hour)), time.Now().Add(time.Hour)
+	paths = paths[:0]
+	if _, err := s.GetProjects(); err != nil {
+		t.Fatal(err)
+	}
+	expected := []string{"GET /v3/projects", "POST /v3/access/users/authenticate", "GET /v3/projects"}
+	if fmt.Sprint(paths) != fmt.Sprint(expected) {
+		t.Errorf("The request must be sent again after a new login (%v)", paths)
+	}
+}
+
+func TestServerV2Negotiation(t *testing.T) {
+	paths := []string{}
+	s, stop := newTestHTTPServer(func(w http.ResponseWriter, r *http.Request) {
+		paths = append(paths, r.URL.Path)
+		if r.URL.Path == "/v3/version" {
+			http.NotFound(w, r)
+			return
+		}
+		w.Write([]byte(`[]`))
+	})
+	defer stop()
+
+	if err := s.Login("admin", "secret"); err != nil {
+		t.Fatal(err)
+	}
+	if s.API() != APIv2 {
+		t.Fatal("The v2 API must be negotiated")
+	}
+	if s.endpoint("start_capture") != "start_capture" {
+		t.Error("The v2 endpoints must not be renamed")
+	}
+	s.GetProjects()
+	if paths[len(paths)-1] != "/v2/projects" {
+		t.Errorf("The v2 API must be used (%v)", paths)
+	}
+}
+
+func TestTokenExpiry(t *testing.T) {
+	exp := time.Unix(1700000000, 0)
+	if e := tokenExpiry(newTestToken(1, exp)); !e.Equal(exp) {
+		t.Errorf("This token expiry seems to be misread (%v)", e)
+	}
+	if e := tokenExpiry("not-a-jwt"); !e.IsZero() {
+		t.Errorf("An invalid token must have no expiry (%v)", e)
+	}
+}
+
+func TestServerEndpointURL(t *testing.T) {
+	s := &Server{APIVersion: APIv2, Host: "localhost", Port: 3080}
+	p := &Project{Server: s, UUID: "1"}
+	n := &Node{Project: p, UUID: "2"}
+
+	cases := map[string]string{
+		s.url():                       "http://localhost:3080/v2/projects",
+		p.url("files", "/a b/c?.txt"): "http://localhost:3080/v2/projects/1/files/a%20b/c%3F.txt",
+		n.url("capture/start"):        "http://localhost:3080/v2/projects/1/nodes/2/capture/start",
+		s.endpointURL(url.Values{"name": {"a&b"}}, "projects", "1", "import"): "http://localhost:3080/v2/projects/1/import?name=a%26b",
+		(&Server{APIVersion: APIv2, Host: "::1", Port: 3080}).url():           "http://[::1]:3080/v2/projects",
+	}
+	for got, expected := range cases {
+		if got != expected {
+			t.Errorf("This URL seems to be wrong (%s instead of %s)", got, expected)
+		}
+	}
+}
+
+func TestServerNegotiationRetry(t *testing.T) {
+	// the first negotiation fails with a transient error, which must not stick
+	// the server to the v2 API
+	failures := 1
+	s, stop := newTestHTTPServer(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/v3/version" && failures > 0 {
+			failures--
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		w.Write([]byte(`{"local": false, "version": "3.0.0"}`))
+	})
+	defer stop()
+
+	if s.API() != APIv2 {
+		t.Error("v2 must be assumed while the negotiation fails")
+	}
+	if s.API() != APIv3 {
+		t.Error("The negotiation must be tried again after a transient error")
+	}
+}
+
+func TestServerLoginUnreachable(t *testing.T) {
+	s := newTestUnreachableServer()
+	s.APIVersion = 0
+	if err := s.Login("admin", "secret"); err == nil {
+		t.Error("Logging in an unreachable server must fail")
+	}
+}
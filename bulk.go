@@ -0,0 +1,117 @@
+package gogns3
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// DefaultBulkParallelism is the number of concurrent operations of a bulk
+// operation when the server does not set it
+const DefaultBulkParallelism = 8
+
+// BulkResult is the result of the operation on one item of a bulk operation.
+// The index is the position of the item in the list given to the bulk
+// operation.
+type BulkResult struct {
+	Err   error
+	Index int
+	Name  string
+}
+
+// BulkError is returned when some operations of a bulk operation failed
+type BulkError struct {
+	Failed []BulkResult
+	Total  int
+}
+
+func (e *BulkError) Error() string {
+	messages := []string{}
+	for _, r := range e.Failed {
+		messages = append(messages, r.Name+": "+r.Err.Error())
+	}
+	return fmt.Sprintf("%d of %d operations failed: %s", len(e.Failed), e.Total, strings.Join(messages, "; "))
+}
+
+// bulk runs an operation on n items concurrently, with the bulk parallelism
+// of the server. The name of each item is read once its operation is done.
+func (p *Project) bulk(n int, operation func(idx int) error, name func(idx int) string) ([]BulkResult, error) {
+	parallelism := p.Server.BulkParallelism
+	if parallelism <= 0 {
+		parallelism = DefaultBulkParallelism
+	}
+
+	results := make([]BulkResult, n)
+	slots := make(chan struct{}, parallelism)
+	wg := sync.WaitGroup{}
+	for idx := 0; idx < n; idx++ {
+		wg.Add(1)
+		slots <- struct{}{}
+		go func(idx int) {
+			defer wg.Done()
+			err := operation(idx)
+			results[idx] = BulkResult{Err: err, Index: idx, Name: name(idx)}
+			<-slots
+		}(idx)
+	}
+	wg.Wait()
+
+	bulkError := &BulkError{Total: n}
+	for _, r := range results {
+		if r.Err != nil {
+			bulkError.Failed = append(bulkError.Failed, r)
+		}
+	}
+	if len(bulkError.Failed) != 0 {
+		return results, bulkError
+	}
+	return results, nil
+}
+
+// CreateNodes creates nodes in the project concurrently. The nodes are updated
+// in place as with Create(). The error is a BulkError if some nodes could not
+// be created.
+func (p *Project) CreateNodes(nodes []Node) ([]BulkResult, error) {
+	return p.bulk(len(nodes), func(idx int) error {
+		nodes[idx].Project = p
+		return nodes[idx].Create()
+	}, func(idx int) string {
+		return "node " + nodes[idx].Name
+	})
+}
+
+// CreateLinks creates links in the project concurrently. The links are updated
+// in place as with Create(). The error is a BulkError if some links could not
+// be created.
+func (p *Project) CreateLinks(links []Link) ([]BulkResult, error) {
+	return p.bulk(len(links), func(idx int) error {
+		links[idx].Project = p
+		return links[idx].Create()
+	}, func(idx int) string {
+		return "link #" + strconv.Itoa(idx)
+	})
+}
+
+// DeleteNodes deletes concurrently the nodes of the project selected by the
+// filter, or all the nodes if the filter is nil. The links of the deleted
+// nodes are deleted by the server. The results are given in the order of
+// GetNodes(). The error is a BulkError if some nodes could not be deleted.
+func (p *Project) DeleteNodes(filter func(Node) bool) ([]BulkResult, error) {
+	all, err := p.GetNodes()
+	if err != nil {
+		return nil, err
+	}
+	nodes := []Node{}
+	for _, n := range all {
+		if filter == nil || filter(n) {
+			nodes = append(nodes, n)
+		}
+	}
+
+	return p.bulk(len(nodes), func(idx int) error {
+		return nodes[idx].Delete()
+	}, func(idx int) string {
+		return "node " + nodes[idx].Name
+	})
+}
@@ -0,0 +1,156 @@
+package gogns3
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// newTestNodesServer starts a fake server keeping the nodes of project 1 in
+// memory. The nodes named "fail" cannot be created nor deleted.
+func newTestNodesServer() (*Server, func()) {
+	mu := sync.Mutex{}
+	nodes := map[string]map[string]interface{}{}
+	next := 0
+
+	return newTestHTTPServer(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		time.Sleep(time.Millisecond)
+
+		id := strings.TrimPrefix(strings.TrimPrefix(r.URL.Path, "/v2/projects/1/nodes"), "/")
+		switch {
+		case r.URL.Path == "/v2/projects/1/links" && r.Method == "POST":
+			w.WriteHeader(http.StatusCreated)
+			w.Write([]byte(`{"link_id": "l"}`))
+		case id == "" && r.Method == "GET":
+			list := []map[string]interface{}{}
+			for _, n := range nodes {
+				list = append(list, n)
+			}
+			json.NewEncoder(w).Encode(list)
+		case id == "" && r.Method == "POST":
+			n := map[string]interface{}{}
+			json.NewDecoder(r.Body).Decode(&n)
+			if n["name"] == "fail" {
+				w.WriteHeader(http.StatusConflict)
+				w.Write([]byte(`{"message": "Node name is already used", "status": 409}`))
+				return
+			}
+			next++
+			n["node_id"] = strconv.Itoa(next)
+			nodes[n["node_id"].(string)] = n
+			w.WriteHeader(http.StatusCreated)
+			json.NewEncoder(w).Encode(n)
+		case r.Method == "GET" && nodes[id] != nil:
+			json.NewEncoder(w).Encode(nodes[id])
+		case r.Method == "PUT" && nodes[id] != nil:
+			update := map[string]interface{}{}
+			json.NewDecoder(r.Body).Decode(&update)
+			for key, value := range update {
+				nodes[id][key] = value
+			}
+			json.NewEncoder(w).Encode(nodes[id])
+		case r.Method == "DELETE" && nodes[id] != nil:
+			if nodes[id]["name"] == "fail" {
+				w.WriteHeader(http.StatusConflict)
+				w.Write([]byte(`{"message": "Node is locked", "status": 409}`))
+				return
+			}
+			delete(nodes, id)
+			w.WriteHeader(http.StatusNoContent)
+		default:
+			http.NotFound(w, r)
+		}
+	})
+}
+
+func TestProjectCreateNodes(t *testing.T) {
+	s, stop := newTestNodesServer()
+	defer stop()
+	s.APIVersion, s.BulkParallelism = APIv2, 4
+	p := &Project{Name: "gogns3", Server: s, UUID: "1"}
+
+	nodes := []Node{}
+	for i := 0; i < 20; i++ {
+		nodes = append(nodes, Node{Name: "PC" + strconv.Itoa(i), NodeType: "vpcs", ComputeID: "local"})
+	}
+	results, err := p.CreateNodes(nodes)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(results) != 20 {
+		t.Fatalf("There must be a result per node (%d)", len(results))
+	}
+	for idx, n := range nodes {
+		if n.UUID == "" || n.Project != p || results[idx].Index != idx || results[idx].Name != "node "+n.Name {
+			t.Errorf("This node seems to be miscreated (%+v, %+v)", n, results[idx])
+		}
+	}
+
+	links := []Link{
+		{Nodes: []LinkNode{{NodeID: nodes[0].UUID}, {NodeID: nodes[1].UUID}}},
+		{Nodes: []LinkNode{{NodeID: nodes[2].UUID}, {NodeID: nodes[3].UUID}}},
+	}
+	if _, err := p.CreateLinks(links); err != nil {
+		t.Fatal(err)
+	}
+	if links[1].UUID != "l" {
+		t.Errorf("This link seems to be miscreated (%+v)", links[1])
+	}
+}
+
+func TestProjectCreateNodesError(t *testing.T) {
+	s, stop := newTestNodesServer()
+	defer stop()
+	s.APIVersion = APIv2
+	p := &Project{Name: "gogns3", Server: s, UUID: "1"}
+
+	nodes := []Node{{Name: "PC1"}, {Name: "fail"}, {Name: "PC2"}}
+	results, err := p.CreateNodes(nodes)
+	var bulkError *BulkError
+	if !errors.As(err, &bulkError) {
+		t.Fatalf("A BulkError must be returned (%v)", err)
+	}
+	if bulkError.Total != 3 || len(bulkError.Failed) != 1 || bulkError.Failed[0].Index != 1 {
+		t.Errorf("This error seems to be wrong (%+v)", bulkError)
+	}
+	if err.Error() != "1 of 3 operations failed: node fail: Server error #409: Node name is already used" {
+		t.Errorf("This error seems to be misformatted (%s)", err)
+	}
+	if results[0].Err != nil || results[2].Err != nil || nodes[2].UUID == "" {
+		t.Error("The other nodes must have been created")
+	}
+}
+
+func TestProjectDeleteNodes(t *testing.T) {
+	s, stop := newTestNodesServer()
+	defer stop()
+	s.APIVersion = APIv2
+	p := &Project{Name: "gogns3", Server: s, UUID: "1"}
+
+	p.CreateNodes([]Node{{Name: "PC1"}, {Name: "PC2"}, {Name: "SW1"}})
+	results, err := p.DeleteNodes(func(n Node) bool { return strings.HasPrefix(n.Name, "PC") })
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(results) != 2 {
+		t.Errorf("Only the selected nodes must be deleted (%+v)", results)
+	}
+	nodes, _ := p.GetNodes()
+	if len(nodes) != 1 || nodes[0].Name != "SW1" {
+		t.Errorf("This project seems to be misconfigured (%+v)", nodes)
+	}
+
+	if _, err := p.DeleteNodes(nil); err != nil {
+		t.Fatal(err)
+	}
+	if nodes, _ := p.GetNodes(); len(nodes) != 0 {
+		t.Error("All the nodes must be deleted")
+	}
+}
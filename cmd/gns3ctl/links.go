@@ -0,0 +1,149 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"os/signal"
+	"strings"
+
+	gogns3 "github.com/desnoe/go-gns3"
+)
+
+func linksList(s *gogns3.Server, out *output, args []string) error {
+	if len(args) != 1 {
+		return errUsage
+	}
+	p, err := readProject(s, args[0])
+	if err != nil {
+		return err
+	}
+	nodes, err := p.GetNodes()
+	if err != nil {
+		return err
+	}
+	links, err := p.GetLinks()
+	if err != nil {
+		return err
+	}
+
+	names := map[string]string{}
+	for _, n := range nodes {
+		names[n.UUID] = n.Name
+	}
+	rows := [][]string{}
+	for _, l := range links {
+		endpoints := []string{}
+		for _, ln := range l.Nodes {
+			e := gogns3.LinkEndpoint{Node: names[ln.NodeID], AdapterNumber: ln.AdapterNumber, PortNumber: ln.PortNumber}
+			endpoints = append(endpoints, e.String())
+		}
+		state := "up"
+		if l.Suspend {
+			state = "suspended"
+		}
+		if l.Capturing {
+			state += ", capturing"
+		}
+		rows = append(rows, []string{l.UUID, strings.Join(endpoints, " <-> "), l.LinkType, state})
+	}
+	return out.print(links, []string{"ID", "ENDPOINTS", "TYPE", "STATE"}, rows)
+}
+
+func linksCreate(s *gogns3.Server, out *output, args []string) error {
+	if len(args) != 3 {
+		return errUsage
+	}
+	p, err := readProject(s, args[0])
+	if err != nil {
+		return err
+	}
+
+	l := &gogns3.Link{Project: p}
+	for _, arg := range args[1:] {
+		e, err := gogns3.ParseLinkEndpoint(arg)
+		if err != nil {
+			return err
+		}
+		nodes, err := selectNodes(p, []string{e.Node})
+		if err != nil {
+			return err
+		}
+		l.Nodes = append(l.Nodes, gogns3.LinkNode{NodeID: nodes[0].UUID, AdapterNumber: e.AdapterNumber, PortNumber: e.PortNumber})
+	}
+	if err := l.Create(); err != nil {
+		return err
+	}
+	return out.print(l, []string{"ID", "ENDPOINTS"}, [][]string{{l.UUID, args[1] + " <-> " + args[2]}})
+}
+
+// dataLinkType returns the data link type of the captures on a link type:
+// Cisco HDLC on serial links, Ethernet otherwise
+func dataLinkType(linkType string) string {
+	if linkType == "serial" {
+		return "DLT_C_HDLC"
+	}
+	return "DLT_EN10MB"
+}
+
+// linksCapture captures the packets going through a link and writes them in
+// the pcap format until the capture is interrupted
+func linksCapture(s *gogns3.Server, out *output, args []string) error {
+	flags := flag.NewFlagSet("capture", flag.ContinueOnError)
+	flags.SetOutput(ioutil.Discard)
+	path := flags.String("w", "", "write the packets to this file instead of the standard output")
+	if err := flags.Parse(args); err != nil || flags.NArg() != 2 {
+		return errUsage
+	}
+	p, err := readProject(s, flags.Arg(0))
+	if err != nil {
+		return err
+	}
+	l := &gogns3.Link{Project: p, UUID: flags.Arg(1)}
+	if err := l.Read(); err != nil {
+		return err
+	}
+
+	w := out.w
+	if *path != "" {
+		f, err := os.Create(*path)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		w = f
+	}
+
+	if !l.Capturing {
+		if err := l.StartCapture(dataLinkType(l.LinkType), ""); err != nil {
+			return err
+		}
+		defer l.StopCapture()
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	interrupt := make(chan os.Signal, 1)
+	signal.Notify(interrupt, os.Interrupt)
+	defer signal.Stop(interrupt)
+	go func() {
+		select {
+		case <-interrupt:
+			cancel()
+		case <-ctx.Done():
+		}
+	}()
+
+	stream, err := l.CaptureStream(ctx)
+	if err != nil {
+		return err
+	}
+	defer stream.Close()
+	if _, err := io.Copy(w, stream); err != nil && ctx.Err() == nil {
+		return fmt.Errorf("capture interrupted: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,120 @@
+// Command gns3ctl manages the projects, nodes and links of a GNS3 server from
+// the command line.
+//
+// Usage:
+//
+//	gns3ctl [flags] projects ls|create|delete|open|close [project]
+//	gns3ctl [flags] nodes ls|start|stop|console project [node...]
+//	gns3ctl [flags] links ls|create|capture project [args...]
+//
+// The server is read from a profile of the configuration file (see
+// gogns3.LoadServerFile), then from the GNS3_* environment variables, then from
+// the -host and -port flags, each source overriding the previous one.
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+
+	gogns3 "github.com/desnoe/go-gns3"
+)
+
+const usage = `Usage: gns3ctl [flags] <resource> <command> [args...]
+
+Commands:
+  projects ls
+  projects create|delete|open|close <project>
+  nodes ls <project>
+  nodes start|stop <project> [node...]
+  nodes console <project> <node>
+  links ls <project>
+  links create <project> <node:adapter/port> <node:adapter/port>
+  links capture [-w file] <project> <link>
+
+Flags:
+`
+
+// errUsage is returned when the command line is invalid
+var errUsage = errors.New("invalid command line")
+
+// command is the function executing a command, with the arguments following
+// the command name
+type command func(s *gogns3.Server, out *output, args []string) error
+
+var commands = map[string]map[string]command{
+	"projects": {
+		"ls":     projectsList,
+		"create": projectsCreate,
+		"delete": projectsDelete,
+		"open":   projectsOpen,
+		"close":  projectsClose,
+	},
+	"nodes": {
+		"ls":      nodesList,
+		"start":   nodesStart,
+		"stop":    nodesStop,
+		"console": nodesConsole,
+	},
+	"links": {
+		"ls":      linksList,
+		"create":  linksCreate,
+		"capture": linksCapture,
+	},
+}
+
+func main() {
+	if err := run(os.Args[1:], os.Stdin, os.Stdout, os.Stderr); err != nil {
+		if err != errUsage {
+			fmt.Fprintln(os.Stderr, "gns3ctl:", err)
+		}
+		os.Exit(1)
+	}
+}
+
+// run parses the command line and executes the command
+func run(args []string, stdin io.Reader, stdout io.Writer, stderr io.Writer) error {
+	flags := flag.NewFlagSet("gns3ctl", flag.ContinueOnError)
+	flags.SetOutput(stderr)
+	flags.Usage = func() {
+		fmt.Fprint(stderr, usage)
+		flags.PrintDefaults()
+	}
+	configPath := flags.String("config", gogns3.DefaultConfigPath(), "configuration file")
+	profile := flags.String("profile", "", "profile of the configuration file, defaults to GNS3_PROFILE or the default profile")
+	host := flags.String("host", "", "GNS3 server host, overrides the configuration")
+	port := flags.Int("port", 0, "GNS3 server port, overrides the configuration")
+	format := flags.String("o", "table", "output format: table or json")
+	if err := flags.Parse(args); err != nil {
+		return errUsage
+	}
+
+	args = flags.Args()
+	if len(args) < 2 || commands[args[0]][args[1]] == nil {
+		flags.Usage()
+		return errUsage
+	}
+	if *format != "table" && *format != "json" {
+		flags.Usage()
+		return errUsage
+	}
+
+	s, err := gogns3.LoadServerFile(*configPath, *profile)
+	if err != nil {
+		return err
+	}
+	if *host != "" {
+		s.Host = *host
+	}
+	if *port != 0 {
+		s.Port = *port
+	}
+
+	err = commands[args[0]][args[1]](s, &output{in: stdin, w: stdout, json: *format == "json"}, args[2:])
+	if err == errUsage {
+		flags.Usage()
+	}
+	return err
+}
@@ -0,0 +1,128 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	gogns3 "github.com/desnoe/go-gns3"
+)
+
+func TestRunUsage(t *testing.T) {
+	for _, args := range [][]string{
+		{},
+		{"projects"},
+		{"projects", "rm"},
+		{"-o", "xml", "projects", "ls"},
+		{"-config", "", "nodes", "ls"},
+		{"-config", "", "links", "capture"},
+	} {
+		stderr := &bytes.Buffer{}
+		if err := run(args, nil, ioutil.Discard, stderr); err != errUsage {
+			t.Errorf("This command line must be rejected (%v): %v", args, err)
+		}
+		if !bytes.Contains(stderr.Bytes(), []byte("Usage: gns3ctl")) {
+			t.Errorf("The usage must be printed (%v)", args)
+		}
+	}
+}
+
+func TestOutput(t *testing.T) {
+	v := []map[string]string{{"name": "lab"}}
+	header := []string{"NAME", "ID"}
+	rows := [][]string{{"lab", "1234"}, {"gogns3", "5678"}}
+
+	b := &bytes.Buffer{}
+	(&output{w: b}).print(v, header, rows)
+	if b.String() != "NAME    ID\nlab     1234\ngogns3  5678\n" {
+		t.Errorf("This table seems to be misprinted:\n%s", b)
+	}
+
+	b.Reset()
+	(&output{w: b, json: true}).print(v, header, rows)
+	if b.String() != "[\n  {\n    \"name\": \"lab\"\n  }\n]\n" {
+		t.Errorf("This JSON seems to be misprinted:\n%s", b)
+	}
+}
+
+func TestNodesConsole(t *testing.T) {
+	// the console answers each line after a while, the answer to the last
+	// line must not be cut when the input ends
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer l.Close()
+	go func() {
+		conn, err := l.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		scanner := bufio.NewScanner(conn)
+		for scanner.Scan() {
+			time.Sleep(100 * time.Millisecond)
+			fmt.Fprintf(conn, "you said %s\r\n", strings.TrimSpace(scanner.Text()))
+		}
+	}()
+	console := l.Addr().(*net.TCPAddr).Port
+
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/v2/projects":
+			w.Write([]byte(`[{"name": "lab", "project_id": "p1"}]`))
+		case "/v2/projects/p1/nodes":
+			fmt.Fprintf(w, `[{"name": "PC1", "node_id": "n1", "console": %d, "console_type": "telnet", "console_host": "127.0.0.1"}]`, console)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer ts.Close()
+	u, _ := url.Parse(ts.URL)
+
+	stdout := &bytes.Buffer{}
+	args := []string{"-config", "", "-host", u.Hostname(), "-port", u.Port(), "nodes", "console", "lab", "PC1"}
+	if err := run(args, strings.NewReader("hello\nbye\n"), stdout, ioutil.Discard); err != nil {
+		t.Fatal(err)
+	}
+	if stdout.String() != "you said hello\r\nyou said bye\r\n" {
+		t.Errorf("This console output seems to be cut (%q)", stdout.String())
+	}
+}
+
+func TestNodesAmbiguous(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/v2/projects":
+			w.Write([]byte(`[{"name": "lab", "project_id": "p1"}]`))
+		case "/v2/projects/p1/nodes":
+			w.Write([]byte(`[{"name": "PC1", "node_id": "n1"}, {"name": "PC1", "node_id": "n2"}]`))
+		default:
+			// no node must be started
+			http.NotFound(w, r)
+		}
+	}))
+	defer ts.Close()
+	u, _ := url.Parse(ts.URL)
+
+	var ambiguousError *gogns3.AmbiguousError
+	args := []string{"-config", "", "-host", u.Hostname(), "-port", u.Port(), "nodes", "start", "lab", "PC1"}
+	if err := run(args, nil, ioutil.Discard, ioutil.Discard); !errors.As(err, &ambiguousError) || len(ambiguousError.UUIDs) != 2 {
+		t.Errorf("Selecting a duplicate node name must be ambiguous (%v)", err)
+	}
+}
+
+func TestDataLinkType(t *testing.T) {
+	if dataLinkType("serial") != "DLT_C_HDLC" || dataLinkType("ethernet") != "DLT_EN10MB" || dataLinkType("") != "DLT_EN10MB" {
+		t.Error("The capture data link types seem to be wrong")
+	}
+}
@@ -0,0 +1,175 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"strconv"
+	"time"
+
+	gogns3 "github.com/desnoe/go-gns3"
+)
+
+// selectNodes returns the nodes of the project with the given names, or all
+// the nodes if no name is given. The error is an AmbiguousError if several
+// nodes have one of the names.
+func selectNodes(p *gogns3.Project, names []string) ([]gogns3.Node, error) {
+	if len(names) == 0 {
+		return p.GetNodes()
+	}
+
+	selected := []gogns3.Node{}
+	for _, name := range names {
+		nodes, err := p.FindNodes(gogns3.NodeName(gogns3.Exact(name)))
+		if err != nil {
+			return nil, err
+		}
+		switch len(nodes) {
+		case 0:
+			return nil, fmt.Errorf("node %q does not exist in project %q", name, p.Name)
+		case 1:
+			selected = append(selected, nodes[0])
+		default:
+			ambiguousError := &gogns3.AmbiguousError{Kind: "node", Name: name}
+			for _, n := range nodes {
+				ambiguousError.UUIDs = append(ambiguousError.UUIDs, n.UUID)
+			}
+			return nil, ambiguousError
+		}
+	}
+	return selected, nil
+}
+
+func nodesList(s *gogns3.Server, out *output, args []string) error {
+	if len(args) != 1 {
+		return errUsage
+	}
+	p, err := readProject(s, args[0])
+	if err != nil {
+		return err
+	}
+	nodes, err := p.GetNodes()
+	if err != nil {
+		return err
+	}
+	return printNodes(out, nodes)
+}
+
+func nodesStart(s *gogns3.Server, out *output, args []string) error {
+	return nodesAction(s, out, args, (*gogns3.Node).Start)
+}
+
+func nodesStop(s *gogns3.Server, out *output, args []string) error {
+	return nodesAction(s, out, args, (*gogns3.Node).Stop)
+}
+
+// nodesAction executes an action on the selected nodes of a project and
+// prints them
+func nodesAction(s *gogns3.Server, out *output, args []string, action func(*gogns3.Node) error) error {
+	if len(args) < 1 {
+		return errUsage
+	}
+	p, err := readProject(s, args[0])
+	if err != nil {
+		return err
+	}
+	nodes, err := selectNodes(p, args[1:])
+	if err != nil {
+		return err
+	}
+	for idx := range nodes {
+		if err := action(&nodes[idx]); err != nil {
+			return fmt.Errorf("node %q: %w", nodes[idx].Name, err)
+		}
+	}
+	return printNodes(out, nodes)
+}
+
+// consoleQuietDelay is the time without console output after which the
+// console is closed, once the end of the input is reached
+const consoleQuietDelay = 500 * time.Millisecond
+
+// activityWriter signals each write on a channel
+type activityWriter struct {
+	w        io.Writer
+	activity chan struct{}
+}
+
+func (a activityWriter) Write(b []byte) (int, error) {
+	select {
+	case a.activity <- struct{}{}:
+	default:
+	}
+	return a.w.Write(b)
+}
+
+// nodesConsole connects the standard input and output to the console of a
+// node, line by line, until the end of the input. The console output is then
+// copied until the console is quiet.
+func nodesConsole(s *gogns3.Server, out *output, args []string) error {
+	if len(args) != 2 {
+		return errUsage
+	}
+	p, err := readProject(s, args[0])
+	if err != nil {
+		return err
+	}
+	nodes, err := selectNodes(p, args[1:])
+	if err != nil {
+		return err
+	}
+
+	console, err := nodes[0].OpenConsole(context.Background())
+	if err != nil {
+		return err
+	}
+	copied := make(chan struct{})
+	activity := make(chan struct{}, 1)
+	go func() {
+		defer close(copied)
+		io.Copy(activityWriter{w: out.w, activity: activity}, console)
+	}()
+	// the output is complete once the copy has returned
+	defer func() {
+		console.Close()
+		<-copied
+	}()
+
+	scanner := bufio.NewScanner(out.in)
+	for scanner.Scan() {
+		if err := console.Send(scanner.Text()); err != nil {
+			return err
+		}
+	}
+
+	// let the console answer the last lines
+	idle := time.NewTimer(consoleQuietDelay)
+	defer idle.Stop()
+	for {
+		select {
+		case <-copied:
+			return scanner.Err()
+		case <-activity:
+			if !idle.Stop() {
+				<-idle.C
+			}
+			idle.Reset(consoleQuietDelay)
+		case <-idle.C:
+			return scanner.Err()
+		}
+	}
+}
+
+// printNodes prints a list of nodes
+func printNodes(out *output, nodes []gogns3.Node) error {
+	rows := [][]string{}
+	for _, n := range nodes {
+		console := ""
+		if n.Console != 0 {
+			console = n.ConsoleType + " " + strconv.Itoa(n.Console)
+		}
+		rows = append(rows, []string{n.Name, n.NodeType, n.Status, console, n.UUID})
+	}
+	return out.print(nodes, []string{"NAME", "TYPE", "STATUS", "CONSOLE", "ID"}, rows)
+}
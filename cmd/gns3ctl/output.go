@@ -0,0 +1,36 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+	"text/tabwriter"
+)
+
+// output writes the result of a command either as a table or as JSON. The
+// input is the standard input, for the commands reading it.
+type output struct {
+	in   io.Reader
+	w    io.Writer
+	json bool
+}
+
+// print writes the rows as a table, or the value as JSON
+func (o *output) print(v interface{}, header []string, rows [][]string) error {
+	if o.json {
+		b, err := json.MarshalIndent(v, "", "  ")
+		if err != nil {
+			return err
+		}
+		_, err = fmt.Fprintf(o.w, "%s\n", b)
+		return err
+	}
+
+	tw := tabwriter.NewWriter(o.w, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(tw, strings.Join(header, "\t"))
+	for _, row := range rows {
+		fmt.Fprintln(tw, strings.Join(row, "\t"))
+	}
+	return tw.Flush()
+}
@@ -0,0 +1,85 @@
+package main
+
+import (
+	gogns3 "github.com/desnoe/go-gns3"
+)
+
+// readProject reads the project with the given name on the server
+func readProject(s *gogns3.Server, name string) (*gogns3.Project, error) {
+	p := &gogns3.Project{Name: name, Server: s}
+	if err := p.Read(); err != nil {
+		return nil, err
+	}
+	return p, nil
+}
+
+func projectsList(s *gogns3.Server, out *output, args []string) error {
+	if len(args) != 0 {
+		return errUsage
+	}
+	projects, err := s.GetProjects()
+	if err != nil {
+		return err
+	}
+
+	rows := [][]string{}
+	for _, p := range projects {
+		rows = append(rows, []string{p.Name, p.UUID, p.Status})
+	}
+	return out.print(projects, []string{"NAME", "ID", "STATUS"}, rows)
+}
+
+func projectsCreate(s *gogns3.Server, out *output, args []string) error {
+	if len(args) != 1 {
+		return errUsage
+	}
+	p := &gogns3.Project{Name: args[0], Server: s}
+	if err := p.Create(); err != nil {
+		return err
+	}
+	return printProject(out, p)
+}
+
+func projectsDelete(s *gogns3.Server, out *output, args []string) error {
+	if len(args) != 1 {
+		return errUsage
+	}
+	p, err := readProject(s, args[0])
+	if err != nil {
+		return err
+	}
+	return p.Delete()
+}
+
+func projectsOpen(s *gogns3.Server, out *output, args []string) error {
+	if len(args) != 1 {
+		return errUsage
+	}
+	p, err := readProject(s, args[0])
+	if err != nil {
+		return err
+	}
+	if err := p.Open(); err != nil {
+		return err
+	}
+	return printProject(out, p)
+}
+
+func projectsClose(s *gogns3.Server, out *output, args []string) error {
+	if len(args) != 1 {
+		return errUsage
+	}
+	p, err := readProject(s, args[0])
+	if err != nil {
+		return err
+	}
+	if err := p.Close(); err != nil {
+		return err
+	}
+	return printProject(out, p)
+}
+
+// printProject prints a single project
+func printProject(out *output, p *gogns3.Project) error {
+	return out.print(p, []string{"NAME", "ID", "STATUS"}, [][]string{{p.Name, p.UUID, p.Status}})
+}
@@ -0,0 +1,72 @@
+package gogns3
+
+import (
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+)
+
+// These tests share a server between goroutines, they are meant to be run
+// with the race detector: go test -race
+
+func TestServerConcurrentNodes(t *testing.T) {
+	s, stop := newTestNodesServer()
+	defer stop()
+	s.MaxInFlight, s.RateLimit, s.RateBurst = 4, 1000, 10
+	p := &Project{Name: "gogns3", Server: s, UUID: "1"}
+
+	wg := sync.WaitGroup{}
+	for i := 0; i < 16; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			n := Node{Name: "PC" + strconv.Itoa(i), NodeType: "vpcs", Project: p}
+			if err := n.Create(); err != nil {
+				t.Error(err)
+				return
+			}
+			n.X = i
+			if err := n.Update(); err != nil || n.UUID == "" || n.X != i {
+				t.Errorf("This node seems to be misupdated (%+v, %v)", n, err)
+			}
+			if _, err := p.GetNodes(); err != nil {
+				t.Error(err)
+			}
+			if err := n.Delete(); err != nil {
+				t.Error(err)
+			}
+		}(i)
+	}
+	wg.Wait()
+
+	if nodes, _ := p.GetNodes(); len(nodes) != 0 {
+		t.Errorf("All the nodes must have been deleted (%d)", len(nodes))
+	}
+}
+
+func TestServerConcurrentLogin(t *testing.T) {
+	paths := []string{}
+	// the tokens expire within the refresh margin, they are refreshed
+	// concurrently
+	s, stop := newTestV3Server(time.Second, &paths)
+	defer stop()
+	if err := s.Login("admin", "secret"); err != nil {
+		t.Fatal(err)
+	}
+
+	wg := sync.WaitGroup{}
+	for i := 0; i < 16; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if _, err := s.GetProjects(); err != nil {
+				t.Error(err)
+			}
+			if _, err := s.Version(); err != nil {
+				t.Error(err)
+			}
+		}()
+	}
+	wg.Wait()
+}
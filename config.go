@@ -0,0 +1,198 @@
+package gogns3
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strconv"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Default values of a server which are not set by a profile
+const (
+	DefaultHost   = "localhost"
+	DefaultPort   = 3080
+	DefaultScheme = "http"
+)
+
+// ErrProfileNotFound is returned when a profile is not defined in the
+// configuration
+var ErrProfileNotFound = errors.New("profile not found")
+
+// Config is the configuration file describing the GNS3 servers, e.g.
+//
+//	default: lab
+//	profiles:
+//	  lab:
+//	    host: 172.16.213.128
+//	    port: 3080
+//	    max_attempts: 3
+//	  prod:
+//	    host: gns3.example.com
+//	    scheme: https
+//	    port: 443
+//	    user: admin
+//	    password: secret
+//	    compute_id: vm
+//	    api_version: 3
+type Config struct {
+	Default  string             `yaml:"default"`
+	Profiles map[string]Profile `yaml:"profiles"`
+}
+
+// Profile describes how to reach a GNS3 server
+type Profile struct {
+	APIVersion  int    `yaml:"api_version"`
+	ComputeID   string `yaml:"compute_id"`
+	Host        string `yaml:"host"`
+	MaxAttempts int    `yaml:"max_attempts"`
+	Password    string `yaml:"password"`
+	Port        int    `yaml:"port"`
+	Scheme      string `yaml:"scheme"`
+	User        string `yaml:"user"`
+}
+
+// DefaultConfigPath returns the path of the configuration file: the value of
+// the GNS3_CONFIG environment variable if set, gns3/profiles.yaml in the user
+// configuration directory otherwise
+func DefaultConfigPath() string {
+	if path, ok := os.LookupEnv("GNS3_CONFIG"); ok {
+		return path
+	}
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(dir, "gns3", "profiles.yaml")
+}
+
+// LoadConfig reads a configuration file
+func LoadConfig(path string) (*Config, error) {
+	b, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	c := &Config{}
+	decoder := yaml.NewDecoder(bytes.NewReader(b))
+	decoder.KnownFields(true)
+	if err := decoder.Decode(c); err != nil && err != io.EOF {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	for name, profile := range c.Profiles {
+		if profile.APIVersion != 0 && profile.APIVersion != APIv2 && profile.APIVersion != APIv3 {
+			return nil, fmt.Errorf("%s: profile %q: invalid API version %d", path, name, profile.APIVersion)
+		}
+		if profile.Scheme != "" && profile.Scheme != "http" && profile.Scheme != "https" {
+			return nil, fmt.Errorf("%s: profile %q: invalid scheme %q", path, name, profile.Scheme)
+		}
+	}
+	return c, nil
+}
+
+// Server builds the server described by a profile. The default profile is used
+// when the name is empty, and the default values when there is no default
+// profile.
+func (c *Config) Server(name string) (*Server, error) {
+	if name == "" {
+		name = c.Default
+	}
+	profile := Profile{}
+	if name != "" {
+		var ok bool
+		if profile, ok = c.Profiles[name]; !ok {
+			return nil, fmt.Errorf("%w: %s", ErrProfileNotFound, name)
+		}
+	}
+
+	s := &Server{
+		APIVersion: profile.APIVersion,
+		ComputeID:  profile.ComputeID,
+		Host:       profile.Host,
+		Password:   profile.Password,
+		Port:       profile.Port,
+		Scheme:     profile.Scheme,
+		User:       profile.User,
+	}
+	if s.Host == "" {
+		s.Host = DefaultHost
+	}
+	if s.Port == 0 {
+		s.Port = DefaultPort
+	}
+	if s.Scheme == "" {
+		s.Scheme = DefaultScheme
+	}
+	if profile.MaxAttempts > 0 {
+		policy := DefaultRetryPolicy
+		policy.MaxAttempts = profile.MaxAttempts
+		s.RetryPolicy = &policy
+	}
+	return s, nil
+}
+
+// LoadServer builds the server described by a profile of the default
+// configuration file, see LoadServerFile
+func LoadServer(profile string) (*Server, error) {
+	return LoadServerFile(DefaultConfigPath(), profile)
+}
+
+// LoadServerFile builds the server described by a profile of a configuration
+// file. The GNS3_PROFILE environment variable selects the profile when none is
+// given. A missing file is not an error unless a profile is requested.
+//
+// The following environment variables override the values of the profile:
+// GNS3_HOST, GNS3_PORT, GNS3_SCHEME, GNS3_USER, GNS3_PASSWORD and
+// GNS3_COMPUTE_ID.
+func LoadServerFile(path string, profile string) (*Server, error) {
+	if profile == "" {
+		profile = os.Getenv("GNS3_PROFILE")
+	}
+
+	c := &Config{}
+	if path != "" {
+		var err error
+		if c, err = LoadConfig(path); os.IsNotExist(err) && profile == "" {
+			c = &Config{}
+		} else if err != nil {
+			return nil, err
+		}
+	}
+
+	s, err := c.Server(profile)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.overrideFromEnv(); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// overrideFromEnv overrides the server values with the environment variables
+func (s *Server) overrideFromEnv() error {
+	for env, value := range map[string]*string{
+		"GNS3_COMPUTE_ID": &s.ComputeID,
+		"GNS3_HOST":       &s.Host,
+		"GNS3_PASSWORD":   &s.Password,
+		"GNS3_SCHEME":     &s.Scheme,
+		"GNS3_USER":       &s.User,
+	} {
+		if v, ok := os.LookupEnv(env); ok {
+			*value = v
+		}
+	}
+	if v, ok := os.LookupEnv("GNS3_PORT"); ok {
+		port, err := strconv.Atoi(v)
+		if err != nil {
+			return fmt.Errorf("GNS3_PORT: %w", err)
+		}
+		s.Port = port
+	}
+	return nil
+}
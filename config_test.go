@@ -0,0 +1,160 @@
+package gogns3
+
+import (
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+const testConfig = `default: lab
+profiles:
+  lab:
+    host: 172.16.213.128
+    port: 3080
+    max_attempts: 3
+  prod:
+    host: gns3.example.com
+    scheme: https
+    port: 443
+    user: admin
+    password: secret
+    compute_id: vm
+`
+
+// setTestEnv sets or unsets (nil value) environment variables and returns a
+// function restoring their previous values
+func setTestEnv(env map[string]*string) func() {
+	previous := map[string]*string{}
+	for key, value := range env {
+		if v, ok := os.LookupEnv(key); ok {
+			previous[key] = &v
+		} else {
+			previous[key] = nil
+		}
+		if value == nil {
+			os.Unsetenv(key)
+		} else {
+			os.Setenv(key, *value)
+		}
+	}
+	return func() {
+		for key, value := range previous {
+			if value == nil {
+				os.Unsetenv(key)
+			} else {
+				os.Setenv(key, *value)
+			}
+		}
+	}
+}
+
+func writeTestConfig(t *testing.T, content string) (string, func()) {
+	dir, err := ioutil.TempDir("", "gogns3")
+	if err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(dir, "profiles.yaml")
+	if err := ioutil.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+	return path, func() { os.RemoveAll(dir) }
+}
+
+func TestConfigServer(t *testing.T) {
+	path, remove := writeTestConfig(t, testConfig)
+	defer remove()
+
+	c, err := LoadConfig(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	s, err := c.Server("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s.Host != "172.16.213.128" || s.Port != 3080 || s.Scheme != "http" {
+		t.Errorf("The default profile seems to be misloaded (%+v)", s)
+	}
+	if s.RetryPolicy == nil || s.RetryPolicy.MaxAttempts != 3 || DefaultRetryPolicy.MaxAttempts == 3 {
+		t.Errorf("The retry policy of the default profile seems to be misloaded (%+v)", s.RetryPolicy)
+	}
+
+	s, err = c.Server("prod")
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := Server{ComputeID: "vm", Host: "gns3.example.com", Password: "secret", Port: 443, Scheme: "https", User: "admin"}
+	if *s != expected {
+		t.Errorf("This profile seems to be misloaded (%+v)", s)
+	}
+	if s.url() != "https://gns3.example.com:443/v2/projects" {
+		t.Errorf("This server URL seems to be wrong (%s)", s.url())
+	}
+
+	if _, err := c.Server("fakefakefake"); !errors.Is(err, ErrProfileNotFound) {
+		t.Errorf("An unknown profile must be rejected (%v)", err)
+	}
+}
+
+func TestConfigError(t *testing.T) {
+	for _, content := range []string{
+		"profiles:\n  lab:\n    hostname: 172.16.213.128\n",
+		"profiles:\n  lab:\n    scheme: ftp\n",
+		"profiles: [lab]\n",
+	} {
+		path, remove := writeTestConfig(t, content)
+		if _, err := LoadConfig(path); err == nil {
+			t.Errorf("This configuration must be rejected:\n%s", content)
+		}
+		remove()
+	}
+}
+
+func TestLoadServerFile(t *testing.T) {
+	path, remove := writeTestConfig(t, testConfig)
+	defer remove()
+
+	host, port, profile := "10.0.0.1", "3081", "prod"
+	defer setTestEnv(map[string]*string{
+		"GNS3_COMPUTE_ID": nil,
+		"GNS3_HOST":       &host,
+		"GNS3_PASSWORD":   nil,
+		"GNS3_PORT":       &port,
+		"GNS3_PROFILE":    &profile,
+		"GNS3_SCHEME":     nil,
+		"GNS3_USER":       nil,
+	})()
+
+	s, err := LoadServerFile(path, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s.Host != "10.0.0.1" || s.Port != 3081 || s.Scheme != "https" || s.User != "admin" {
+		t.Errorf("The environment must override the profile selected by GNS3_PROFILE (%+v)", s)
+	}
+
+	s, err = LoadServerFile(path, "lab")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s.Host != "10.0.0.1" || s.Scheme != "http" {
+		t.Errorf("The requested profile must be loaded (%+v)", s)
+	}
+
+	// a missing file is only an error when a profile is requested
+	missing := filepath.Join(filepath.Dir(path), "fakefakefake.yaml")
+	if _, err := LoadServerFile(missing, "lab"); err == nil {
+		t.Error("A profile of a missing file must be rejected")
+	}
+	os.Unsetenv("GNS3_PROFILE")
+	s, err = LoadServerFile(missing, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s.Host != "10.0.0.1" || s.Port != 3081 || s.Scheme != DefaultScheme {
+		t.Errorf("The environment must be used without a configuration file (%+v)", s)
+	}
+}
@@ -0,0 +1,253 @@
+package gogns3
+
+import (
+	"context"
+	"errors"
+	"net"
+	"regexp"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// Telnet commands and options handled by the console sessions (RFC 854)
+const (
+	telnetSE   = 240
+	telnetSB   = 250
+	telnetWILL = 251
+	telnetWONT = 252
+	telnetDO   = 253
+	telnetDONT = 254
+	telnetIAC  = 255
+
+	telnetOptionEcho            = 1
+	telnetOptionSuppressGoAhead = 3
+)
+
+// telnet parser states
+const (
+	telnetStateData = iota
+	telnetStateIAC
+	telnetStateOption
+	telnetStateSB
+	telnetStateSBIAC
+)
+
+// ErrConsoleUnsupported is returned when the console of a node is not a telnet
+// console, or when the node has no console
+var ErrConsoleUnsupported = errors.New("node console is not a telnet console")
+
+// ConsoleSession is a telnet session to the console of a node. It implements
+// net.Conn: Read returns the console output with the telnet negotiation
+// removed, Write escapes the data sent to the console.
+type ConsoleSession struct {
+	net.Conn
+
+	writeMutex sync.Mutex
+	pending    []byte
+	raw        []byte
+	state      int
+	command    byte
+	local      map[byte]bool
+	remote     map[byte]bool
+}
+
+// NewConsoleSession starts a telnet session on an established connection
+func NewConsoleSession(conn net.Conn) *ConsoleSession {
+	return &ConsoleSession{
+		Conn:   conn,
+		raw:    make([]byte, 4096),
+		local:  map[byte]bool{},
+		remote: map[byte]bool{},
+	}
+}
+
+// OpenConsole opens a telnet session to the console of the node. The node must
+// be started and its console type must be telnet. The context is only used to
+// establish the connection.
+func (n *Node) OpenConsole(ctx context.Context) (*ConsoleSession, error) {
+	if n.ConsoleType != "telnet" || n.Console == 0 {
+		return nil, ErrConsoleUnsupported
+	}
+
+	// the console host is the address the console listens on, which may be a
+	// wildcard address when the compute is the controller itself
+	host := n.ConsoleHost
+	if ip := net.ParseIP(host); host == "" || (ip != nil && ip.IsUnspecified()) {
+		host = n.Project.Server.Host
+	}
+
+	dialer := net.Dialer{}
+	conn, err := dialer.DialContext(ctx, "tcp", net.JoinHostPort(host, strconv.Itoa(n.Console)))
+	if err != nil {
+		return nil, err
+	}
+	return NewConsoleSession(conn), nil
+}
+
+// Read reads the console output
+func (c *ConsoleSession) Read(p []byte) (int, error) {
+	for len(c.pending) == 0 {
+		if err := c.fill(); err != nil && len(c.pending) == 0 {
+			return 0, err
+		}
+	}
+	n := copy(p, c.pending)
+	c.pending = c.pending[n:]
+	return n, nil
+}
+
+// Write writes data to the console, escaping the telnet IAC bytes
+func (c *ConsoleSession) Write(p []byte) (int, error) {
+	escaped := make([]byte, 0, len(p))
+	for _, b := range p {
+		if b == telnetIAC {
+			escaped = append(escaped, telnetIAC)
+		}
+		escaped = append(escaped, b)
+	}
+
+	c.writeMutex.Lock()
+	defer c.writeMutex.Unlock()
+	if _, err := c.Conn.Write(escaped); err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
+
+// Send sends a line to the console
+func (c *ConsoleSession) Send(line string) error {
+	_, err := c.Write([]byte(line + "\r\n"))
+	return err
+}
+
+// Expect reads the console output until it matches the regular expression.
+// It returns the output up to the end of the match, the rest being kept for
+// the next reads. If the context is done first, the output read so far is
+// returned along with the context error.
+func (c *ConsoleSession) Expect(ctx context.Context, re *regexp.Regexp) (string, error) {
+	done := make(chan struct{})
+	stopped := make(chan struct{})
+	go func() {
+		defer close(stopped)
+		select {
+		case <-ctx.Done():
+			// unblock the pending read
+			c.Conn.SetReadDeadline(time.Now())
+		case <-done:
+		}
+	}()
+	defer func() {
+		close(done)
+		<-stopped
+		c.Conn.SetReadDeadline(time.Time{})
+	}()
+
+	for {
+		if loc := re.FindIndex(c.pending); loc != nil {
+			output := string(c.pending[:loc[1]])
+			c.pending = c.pending[loc[1]:]
+			return output, nil
+		}
+		if err := c.fill(); err != nil {
+			if ctx.Err() != nil {
+				err = ctx.Err()
+			}
+			return string(c.pending), err
+		}
+	}
+}
+
+// fill reads from the connection and appends the decoded data to the pending
+// output, answering the telnet negotiation on the way
+func (c *ConsoleSession) fill() error {
+	n, err := c.Conn.Read(c.raw)
+	replies := []byte{}
+	for _, b := range c.raw[:n] {
+		switch c.state {
+		case telnetStateData:
+			switch b {
+			case telnetIAC:
+				c.state = telnetStateIAC
+			case 0:
+				// NUL bytes are only padding (e.g. after a CR)
+			default:
+				c.pending = append(c.pending, b)
+			}
+		case telnetStateIAC:
+			switch b {
+			case telnetIAC:
+				c.pending = append(c.pending, b)
+				c.state = telnetStateData
+			case telnetWILL, telnetWONT, telnetDO, telnetDONT:
+				c.command = b
+				c.state = telnetStateOption
+			case telnetSB:
+				c.state = telnetStateSB
+			default:
+				// other commands (NOP, GA, ...) carry no data
+				c.state = telnetStateData
+			}
+		case telnetStateOption:
+			replies = append(replies, c.negotiate(c.command, b)...)
+			c.state = telnetStateData
+		case telnetStateSB:
+			if b == telnetIAC {
+				c.state = telnetStateSBIAC
+			}
+		case telnetStateSBIAC:
+			if b == telnetSE {
+				c.state = telnetStateData
+			} else {
+				c.state = telnetStateSB
+			}
+		}
+	}
+
+	if len(replies) > 0 {
+		c.writeMutex.Lock()
+		_, werr := c.Conn.Write(replies)
+		c.writeMutex.Unlock()
+		if err == nil {
+			err = werr
+		}
+	}
+	return err
+}
+
+// negotiate answers a telnet option request. The server may echo and suppress
+// go-ahead, the client suppresses go-ahead and refuses everything else. A
+// reply is only sent when the state of the option changes, to avoid loops.
+func (c *ConsoleSession) negotiate(command byte, option byte) []byte {
+	switch command {
+	case telnetWILL:
+		accept := option == telnetOptionEcho || option == telnetOptionSuppressGoAhead
+		if accept && !c.remote[option] {
+			c.remote[option] = true
+			return []byte{telnetIAC, telnetDO, option}
+		}
+		if !accept {
+			return []byte{telnetIAC, telnetDONT, option}
+		}
+	case telnetWONT:
+		if c.remote[option] {
+			c.remote[option] = false
+			return []byte{telnetIAC, telnetDONT, option}
+		}
+	case telnetDO:
+		accept := option == telnetOptionSuppressGoAhead
+		if accept && !c.local[option] {
+			c.local[option] = true
+			return []byte{telnetIAC, telnetWILL, option}
+		}
+		if !accept {
+			return []byte{telnetIAC, telnetWONT, option}
+		}
+	case telnetDONT:
+		if c.local[option] {
+			c.local[option] = false
+			return []byte{telnetIAC, telnetWONT, option}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,262 @@
+package gogns3
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"regexp"
+	"strings"
+	"sync"
+)
+
+// ConsoleProfile describes how to drive the console of a given vendor. Prompt
+// must match any prompt the console can show while commands are run (user,
+// enable or configuration mode) at the end of the output.
+type ConsoleProfile struct {
+	Name string
+	// Prompt matches the prompt at the end of the output
+	Prompt *regexp.Regexp
+	// Pager matches the pager shown in the middle of long outputs, which is
+	// answered with PagerResponse
+	Pager         *regexp.Regexp
+	PagerResponse string
+	// UserPrompt matches the unprivileged prompt, EnableCommand is then sent
+	// before running the commands. If the console then asks for a password
+	// with EnablePasswordPrompt, it is answered with EnablePassword.
+	UserPrompt           *regexp.Regexp
+	EnableCommand        string
+	EnablePasswordPrompt *regexp.Regexp
+	EnablePassword       string
+	// SetupCommands are run silently once the console is ready, e.g. to
+	// disable paging
+	SetupCommands []string
+}
+
+// ErrEnableFailed is returned when the privileged mode cannot be entered,
+// usually because the enable password is missing or wrong
+var ErrEnableFailed = errors.New("could not enter the privileged mode")
+
+// CommandOutput is the output of a command run on a console
+type CommandOutput struct {
+	Command string
+	Output  string
+}
+
+// Console profiles of the most common lab node types
+var (
+	ConsoleProfileCiscoIOS = &ConsoleProfile{
+		Name:          "cisco_ios",
+		Prompt:        regexp.MustCompile(`[\w.\-@/:]+(\([\w.\-]+\))?[>#] ?$`),
+		Pager:         regexp.MustCompile(` ?--More-- ?$`),
+		PagerResponse: " ",
+		UserPrompt:    regexp.MustCompile(`[\w.\-@/:]+> ?$`),
+		EnableCommand: "enable",
+		// the enable password is set on a copy of the profile
+		EnablePasswordPrompt: regexp.MustCompile(`[Pp]assword: ?$`),
+		SetupCommands:        []string{"terminal length 0"},
+	}
+	ConsoleProfileJuniper = &ConsoleProfile{
+		Name:          "juniper",
+		Prompt:        regexp.MustCompile(`([\w.\-]+@[\w.\-]+[>#%]|[>#%]) ?$`),
+		Pager:         regexp.MustCompile(`---\(more( \d+%)?\)--- ?$`),
+		PagerResponse: " ",
+		SetupCommands: []string{"set cli screen-length 0"},
+	}
+	ConsoleProfileVPCS = &ConsoleProfile{
+		Name:   "vpcs",
+		Prompt: regexp.MustCompile(`[\w.\-]+> ?$`),
+	}
+	ConsoleProfileLinux = &ConsoleProfile{
+		Name:   "linux",
+		Prompt: regexp.MustCompile(`[\w.\-@:~/\[\] ]*[$#] ?$`),
+		Pager:  regexp.MustCompile(`(--More--|\(END\)) ?$`),
+		// quit the pager rather than paging through the output
+		PagerResponse: "q",
+	}
+)
+
+// junosImage matches the disk images and symbols of the Juniper appliances
+var junosImage = regexp.MustCompile(`(?i)junos|juniper|vsrx|vqfx|\bvmx`)
+
+// ConsoleProfileFor returns the console profile matching the node type. Nodes
+// running an unknown system are driven with the Linux profile.
+func ConsoleProfileFor(nodeType string) *ConsoleProfile {
+	switch nodeType {
+	case "dynamips", "iou":
+		return ConsoleProfileCiscoIOS
+	case "vpcs":
+		return ConsoleProfileVPCS
+	}
+	return ConsoleProfileLinux
+}
+
+// ConsoleProfileForNode returns the console profile matching the node: the
+// Juniper profile for the QEMU nodes running a Junos image (vSRX, vMX, vQFX,
+// ...), the profile of the node type otherwise. The node must have been read
+// from the server for its disk image to be known.
+func ConsoleProfileForNode(n *Node) *ConsoleProfile {
+	if n.NodeType == "qemu" && (junosImage.MatchString(n.Properties.HdaDiskImage) || junosImage.MatchString(n.Symbol)) {
+		return ConsoleProfileJuniper
+	}
+	return ConsoleProfileFor(n.NodeType)
+}
+
+// RunCommands opens the console of the node and runs the commands with the
+// console profile matching the node. See RunCommandsWithProfile.
+func (n *Node) RunCommands(ctx context.Context, commands []string) ([]CommandOutput, error) {
+	return n.RunCommandsWithProfile(ctx, ConsoleProfileForNode(n), commands)
+}
+
+// RunCommandsWithProfile opens the console of the node, runs the commands with
+// the given console profile and closes the console.
+func (n *Node) RunCommandsWithProfile(ctx context.Context, profile *ConsoleProfile, commands []string) ([]CommandOutput, error) {
+	c, err := n.OpenConsole(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer c.Close()
+	return c.RunCommands(ctx, profile, commands)
+}
+
+// RunCommands waits for the console prompt, enters the privileged mode if
+// needed, then runs the commands one by one. The output of each command is
+// returned without the echoed command, the pagers and the final prompt. On
+// error, the outputs of the commands run so far are returned.
+func (c *ConsoleSession) RunCommands(ctx context.Context, profile *ConsoleProfile, commands []string) ([]CommandOutput, error) {
+	// wake the console up, it may be waiting on a prompt printed long ago
+	if err := c.Send(""); err != nil {
+		return nil, err
+	}
+	prompt, err := c.Expect(ctx, profile.Prompt)
+	if err != nil {
+		return nil, err
+	}
+	// drop any other prompt already received, it would end the first command
+	c.pending = nil
+	if profile.UserPrompt != nil && profile.EnableCommand != "" && profile.UserPrompt.MatchString(lastLine(prompt)) {
+		if err := c.enable(ctx, profile); err != nil {
+			return nil, err
+		}
+	}
+	for _, command := range profile.SetupCommands {
+		if _, err := c.runCommand(ctx, profile, command); err != nil {
+			return nil, err
+		}
+	}
+
+	outputs := []CommandOutput{}
+	for _, command := range commands {
+		output, err := c.runCommand(ctx, profile, command)
+		if err != nil {
+			return outputs, err
+		}
+		outputs = append(outputs, CommandOutput{Command: command, Output: output})
+	}
+	return outputs, nil
+}
+
+// enable enters the privileged mode, answering the password prompt if the
+// profile has one. ErrEnableFailed is returned if the password is missing or
+// rejected, or if the console is still in the unprivileged mode afterwards.
+func (c *ConsoleSession) enable(ctx context.Context, profile *ConsoleProfile) error {
+	if err := c.Send(profile.EnableCommand); err != nil {
+		return err
+	}
+	passwordSent := false
+	for {
+		chunk, err := c.Expect(ctx, anyOf(profile.Prompt, profile.EnablePasswordPrompt))
+		if err != nil {
+			return err
+		}
+		if profile.EnablePasswordPrompt != nil && profile.EnablePasswordPrompt.MatchString(chunk) {
+			if passwordSent || profile.EnablePassword == "" {
+				return ErrEnableFailed
+			}
+			if err := c.Send(profile.EnablePassword); err != nil {
+				return err
+			}
+			passwordSent = true
+			continue
+		}
+		if profile.UserPrompt.MatchString(lastLine(chunk)) {
+			return ErrEnableFailed
+		}
+		return nil
+	}
+}
+
+// runCommand sends a command and reads its output up to the next prompt,
+// answering the pagers on the way
+func (c *ConsoleSession) runCommand(ctx context.Context, profile *ConsoleProfile, command string) (string, error) {
+	if err := c.Send(command); err != nil {
+		return "", err
+	}
+
+	expected := anyOf(profile.Prompt, profile.Pager)
+	output := &bytes.Buffer{}
+	for {
+		chunk, err := c.Expect(ctx, expected)
+		if err != nil {
+			return "", err
+		}
+		if profile.Pager != nil {
+			if loc := profile.Pager.FindStringIndex(chunk); loc != nil {
+				output.WriteString(chunk[:loc[0]])
+				if _, err := c.Write([]byte(profile.PagerResponse)); err != nil {
+					return "", err
+				}
+				continue
+			}
+		}
+		loc := profile.Prompt.FindStringIndex(chunk)
+		output.WriteString(chunk[:loc[0]])
+		break
+	}
+	return cleanCommandOutput(output.String(), command), nil
+}
+
+// alternatives caches the regular expressions built by anyOf, so that they are
+// compiled once per profile rather than once per command
+var alternatives = struct {
+	sync.Mutex
+	m map[[2]*regexp.Regexp]*regexp.Regexp
+}{m: map[[2]*regexp.Regexp]*regexp.Regexp{}}
+
+// anyOf returns a regular expression matching either a or b. b may be nil, a
+// is then returned.
+func anyOf(a *regexp.Regexp, b *regexp.Regexp) *regexp.Regexp {
+	if b == nil {
+		return a
+	}
+	alternatives.Lock()
+	defer alternatives.Unlock()
+	key := [2]*regexp.Regexp{a, b}
+	if alternatives.m[key] == nil {
+		alternatives.m[key] = regexp.MustCompile("(?:" + a.String() + ")|(?:" + b.String() + ")")
+	}
+	return alternatives.m[key]
+}
+
+// pagerErasure matches the sequences erasing the pagers from the output
+var pagerErasure = regexp.MustCompile(`[ \x08]*\x08`)
+
+// cleanCommandOutput removes the echoed command, the sequences erasing the
+// pagers and the trailing blank lines of a command output
+func cleanCommandOutput(output string, command string) string {
+	output = strings.Replace(output, "\r\n", "\n", -1)
+	output = strings.Replace(output, "\r", "", -1)
+	output = pagerErasure.ReplaceAllString(output, "")
+	lines := strings.Split(output, "\n")
+	if len(lines) > 0 && strings.TrimSpace(lines[0]) == strings.TrimSpace(command) {
+		lines = lines[1:]
+	}
+	return strings.TrimRight(strings.Join(lines, "\n"), " \n")
+}
+
+func lastLine(s string) string {
+	s = strings.TrimRight(s, "\r\n")
+	if idx := strings.LastIndexAny(s, "\r\n"); idx >= 0 {
+		return s[idx+1:]
+	}
+	return s
+}
@@ -0,0 +1,137 @@
+package gogns3
+
+import (
+	"bufio"
+	"context"
+	"net"
+	"strings"
+	"testing"
+	"time"
+)
+
+// testCiscoConsole is a fake Cisco IOS console answering a few commands
+func testCiscoConsole(conn net.Conn) {
+	testCiscoConsoleWithPassword(conn, "")
+}
+
+// testCiscoConsoleWithPassword is a fake Cisco IOS console asking for an enable
+// password, if not empty
+func testCiscoConsoleWithPassword(conn net.Conn, password string) {
+	r := bufio.NewReader(conn)
+	prompt := "R1>"
+	for {
+		line, err := r.ReadString('\n')
+		if err != nil {
+			return
+		}
+		command := strings.TrimRight(line, "\r\n")
+		conn.Write([]byte(command + "\r\n"))
+		switch command {
+		case "enable":
+			if password != "" {
+				conn.Write([]byte("Password: "))
+				line, err := r.ReadString('\n')
+				if err != nil {
+					return
+				}
+				conn.Write([]byte("\r\n"))
+				if strings.TrimRight(line, "\r\n") != password {
+					conn.Write([]byte("% Bad secrets\r\n\r\n" + prompt))
+					continue
+				}
+			}
+			prompt = "R1#"
+		case "show version":
+			conn.Write([]byte("Cisco IOS Software\r\n --More-- "))
+			if b, _ := r.ReadByte(); b != ' ' {
+				return
+			}
+			conn.Write([]byte("\x08\x08\x08\x08\x08\x08\x08\x08\x08\x08          \x08\x08\x08\x08\x08\x08\x08\x08\x08\x08"))
+			conn.Write([]byte("R1 uptime is 1 day\r\n"))
+		case "show clock":
+			conn.Write([]byte("*10:00:00.000 UTC Mon Oct 19 2026\r\n"))
+		}
+		conn.Write([]byte(prompt))
+	}
+}
+
+func TestConsoleSessionRunCommandsCiscoIOS(t *testing.T) {
+	n := startTestConsole(t, testCiscoConsole)
+	n.NodeType = "dynamips"
+
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+	outputs, err := n.RunCommands(ctx, []string{"show version", "show clock"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(outputs) != 2 {
+		t.Fatalf("Two command outputs were expected (got %d)", len(outputs))
+	}
+	if outputs[0].Command != "show version" {
+		t.Error("This command output seems to be wrong (command != show version)")
+	}
+	if outputs[0].Output != "Cisco IOS Software\nR1 uptime is 1 day" {
+		t.Errorf("This command output seems to be wrong (%q)", outputs[0].Output)
+	}
+	if outputs[1].Output != "*10:00:00.000 UTC Mon Oct 19 2026" {
+		t.Errorf("This command output seems to be wrong (%q)", outputs[1].Output)
+	}
+}
+
+func TestConsoleSessionRunCommandsError(t *testing.T) {
+	n := startTestConsole(t, func(conn net.Conn) {
+		conn.Write([]byte("Press RETURN to get started"))
+		time.Sleep(time.Second)
+	})
+	n.NodeType = "vpcs"
+
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	if _, err := n.RunCommands(ctx, []string{"show ip"}); err != context.DeadlineExceeded {
+		t.Errorf("Running commands must fail when no prompt is found (%v)", err)
+	}
+}
+
+func TestConsoleProfileFor(t *testing.T) {
+	if ConsoleProfileFor("iou") != ConsoleProfileCiscoIOS {
+		t.Error("IOU nodes must be driven with the Cisco IOS profile")
+	}
+	if ConsoleProfileFor("vpcs") != ConsoleProfileVPCS {
+		t.Error("VPCS nodes must be driven with the VPCS profile")
+	}
+	if ConsoleProfileFor("docker") != ConsoleProfileLinux {
+		t.Error("Docker nodes must be driven with the Linux profile")
+	}
+
+	n := &Node{NodeType: "qemu", Properties: NodeProperties{HdaDiskImage: "junos-vsrx3-x86-64-20.4R1.12.qcow2"}}
+	if ConsoleProfileForNode(n) != ConsoleProfileJuniper {
+		t.Error("vSRX nodes must be driven with the Juniper profile")
+	}
+	n.Properties.HdaDiskImage = "debian-11.qcow2"
+	if ConsoleProfileForNode(n) != ConsoleProfileLinux {
+		t.Error("Debian nodes must be driven with the Linux profile")
+	}
+}
+
+func TestConsoleSessionRunCommandsEnablePassword(t *testing.T) {
+	profile := *ConsoleProfileCiscoIOS
+	profile.EnablePassword = "secret"
+	n := startTestConsole(t, func(conn net.Conn) { testCiscoConsoleWithPassword(conn, "secret") })
+
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+	outputs, err := n.RunCommandsWithProfile(ctx, &profile, []string{"show clock"})
+	if err != nil || len(outputs) != 1 || outputs[0].Output != "*10:00:00.000 UTC Mon Oct 19 2026" {
+		t.Errorf("The commands must be run once enabled (%+v, %v)", outputs, err)
+	}
+
+	// without the password, or with a wrong one, the console must not hang
+	for _, password := range []string{"", "wrong"} {
+		profile.EnablePassword = password
+		n = startTestConsole(t, func(conn net.Conn) { testCiscoConsoleWithPassword(conn, "secret") })
+		if _, err := n.RunCommandsWithProfile(ctx, &profile, []string{"show clock"}); err != ErrEnableFailed {
+			t.Errorf("Entering the privileged mode must fail with password %q (%v)", password, err)
+		}
+	}
+}
@@ -0,0 +1,117 @@
+package gogns3
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"io"
+	"net"
+	"regexp"
+	"testing"
+	"time"
+)
+
+// startTestConsole starts a fake telnet console on the loopback interface and
+// returns a node pointing to it. The handler is run for the first connection.
+func startTestConsole(t *testing.T, handler func(net.Conn)) *Node {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	go func() {
+		conn, err := l.Accept()
+		l.Close()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		handler(conn)
+	}()
+
+	return &Node{
+		Console:     l.Addr().(*net.TCPAddr).Port,
+		ConsoleHost: "0.0.0.0",
+		ConsoleType: "telnet",
+		Project:     &Project{Server: &Server{Host: "127.0.0.1"}},
+	}
+}
+
+func TestConsoleSessionNegotiation(t *testing.T) {
+	received := make(chan []byte, 1)
+	n := startTestConsole(t, func(conn net.Conn) {
+		// WILL ECHO, WILL SGA, DO TERMINAL-TYPE, then a prompt containing an
+		// escaped IAC and a subnegotiation to be ignored
+		conn.Write([]byte{telnetIAC, telnetWILL, 1, telnetIAC, telnetWILL, 3, telnetIAC, telnetDO, 24})
+		conn.Write([]byte{'R', '1', telnetIAC, telnetIAC, telnetIAC, telnetSB, 24, 1, telnetIAC, telnetSE, '>'})
+
+		replies := make([]byte, 9)
+		io.ReadFull(conn, replies)
+		line, _ := bufio.NewReader(conn).ReadBytes('\n')
+		received <- append(replies, line...)
+	})
+
+	c, err := n.OpenConsole(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+	output, err := c.Expect(ctx, regexp.MustCompile(`>$`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if output != "R1\xff>" {
+		t.Errorf("The console output seems to be misdecoded (%q)", output)
+	}
+	if err := c.Send("enable"); err != nil {
+		t.Fatal(err)
+	}
+
+	expected := []byte{telnetIAC, telnetDO, 1, telnetIAC, telnetDO, 3, telnetIAC, telnetWONT, 24}
+	expected = append(expected, []byte("enable\r\n")...)
+	select {
+	case b := <-received:
+		if !bytes.Equal(b, expected) {
+			t.Errorf("The console negotiation seems to be wrong (%v)", b)
+		}
+	case <-ctx.Done():
+		t.Error("The console did not receive the negotiation replies")
+	}
+}
+
+func TestConsoleSessionExpectTimeout(t *testing.T) {
+	n := startTestConsole(t, func(conn net.Conn) {
+		conn.Write([]byte("Booting..."))
+		time.Sleep(time.Second)
+	})
+
+	c, err := n.OpenConsole(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	output, err := c.Expect(ctx, regexp.MustCompile(`login:`))
+	if err != context.DeadlineExceeded {
+		t.Errorf("Expect must fail when the context is done (%v)", err)
+	}
+	if output != "Booting..." {
+		t.Errorf("Expect must return the output read so far (%q)", output)
+	}
+}
+
+func TestConsoleSessionError(t *testing.T) {
+	n := Node{
+		Console:     5000,
+		ConsoleType: "vnc",
+	}
+
+	if _, err := n.OpenConsole(context.Background()); err != ErrConsoleUnsupported {
+		t.Error("Opening a VNC console must fail with ErrConsoleUnsupported")
+	}
+}
@@ -0,0 +1,106 @@
+package gogns3
+
+import "encoding/json"
+
+// Drawing is the basic structure used for a GNS3 drawing (shape, text or
+// image described as SVG)
+type Drawing struct {
+	Locked   bool     `json:"locked"`
+	Project  *Project `json:"-"`
+	Rotation int      `json:"rotation"`
+	SVG      string   `json:"svg,omitempty"`
+	UUID     string   `json:"drawing_id,omitempty"`
+	X        int      `json:"x"`
+	Y        int      `json:"y"`
+	Z        int      `json:"z"`
+}
+
+func (d *Drawing) url() string {
+	return d.Project.url("drawings", d.UUID)
+}
+
+// Read reads an existing drawing in the project. Drawings are not named in
+// GNS3, so the drawing is read by UUID.
+func (d *Drawing) Read() error {
+	if d.UUID == "" {
+		return &ServerError{Status: 404, Message: "Drawing does not exist in the project"}
+	}
+	status, content, err := d.Project.Server.HTTPRequest("GET", d.url(), nil)
+	if err != nil {
+		return err
+	}
+	if !(status >= 200 && status < 300) {
+		serverError := ServerError{Status: status}
+		json.Unmarshal(content, &serverError)
+		return &serverError
+	}
+	drawing := Drawing{Project: d.Project}
+	if err := json.Unmarshal(content, &drawing); err != nil {
+		return err
+	}
+	*d = drawing
+	return nil
+}
+
+// Exists checks a drawing exists in the project
+func (d *Drawing) Exists() (bool, error) {
+	// Use a new struct because Read() will overwrite it
+	drawing := Drawing{
+		UUID:    d.UUID,
+		Project: d.Project,
+	}
+
+	err := drawing.Read()
+	return err == nil, err
+}
+
+// Create creates a drawing in the project
+func (d *Drawing) Create() error {
+	b, _ := json.Marshal(d)
+	status, content, err := d.Project.Server.HTTPRequest("POST", d.Project.url("drawings"), b)
+	if err != nil {
+		return err
+	}
+	if !(status >= 200 && status < 300) {
+		serverError := ServerError{Status: status}
+		json.Unmarshal(content, &serverError)
+		return &serverError
+	}
+	json.Unmarshal(content, d)
+	return nil
+}
+
+// Delete deletes a drawing in the project
+func (d *Drawing) Delete() error {
+	status, content, err := d.Project.Server.HTTPRequest("DELETE", d.url(), nil)
+	if err != nil {
+		return err
+	}
+	if !(status >= 200 && status < 300) {
+		serverError := ServerError{Status: status}
+		json.Unmarshal(content, &serverError)
+		return &serverError
+	}
+	return nil
+}
+
+// Update updates a drawing in the project
+func (d *Drawing) Update() error {
+	// the UUID is not sent: a copy is sent so that the drawing is not
+	// modified meanwhile
+	drawing := *d
+	drawing.UUID = ""
+	b, _ := json.Marshal(drawing)
+
+	status, content, err := d.Project.Server.HTTPRequest("PUT", d.url(), b)
+	if err != nil {
+		return err
+	}
+	if !(status >= 200 && status < 300) {
+		serverError := ServerError{Status: status}
+		json.Unmarshal(content, &serverError)
+		return &serverError
+	}
+	json.Unmarshal(content, d)
+	return nil
+}
@@ -0,0 +1,132 @@
+package gogns3
+
+import (
+	"errors"
+	"testing"
+)
+
+const testDrawingSVG = `<svg height="100" width="200"><rect fill="#ffffff" height="100" width="200" /></svg>`
+
+func resetTestDrawing(t *testing.T) *Drawing {
+	d := Drawing{
+		Project: resetTestProject(t),
+		SVG:     testDrawingSVG,
+		X:       10,
+		Y:       20,
+	}
+
+	err := d.Create()
+
+	if err != nil {
+		t.Error("Could not create a new drawing")
+		t.Error(err)
+	}
+
+	return &d
+}
+
+func TestDrawingCreate(t *testing.T) {
+	d := Drawing{
+		Locked:   true,
+		Project:  resetTestProject(t),
+		Rotation: 90,
+		SVG:      testDrawingSVG,
+		X:        10,
+		Y:        20,
+		Z:        2,
+	}
+
+	err := d.Create()
+
+	if err != nil {
+		t.Error("Could not create a new drawing")
+		t.Error(err)
+	}
+	d.Read()
+	if d.Locked != true {
+		t.Error("This drawing seems to be misconfigured (locked != true)")
+	}
+	if d.Rotation != 90 {
+		t.Error("This drawing seems to be misconfigured (rotation != 90)")
+	}
+	if d.X != 10 {
+		t.Error("This drawing seems to be misconfigured (X != 10)")
+	}
+	if d.Y != 20 {
+		t.Error("This drawing seems to be misconfigured (Y != 20)")
+	}
+	if d.Z != 2 {
+		t.Error("This drawing seems to be misconfigured (Z != 2)")
+	}
+}
+
+func TestDrawingExists(t *testing.T) {
+	d := resetTestDrawing(t)
+
+	b, err := d.Exists()
+	if err != nil {
+		t.Error(err)
+	}
+	if !b {
+		t.Error("This drawing must exist on the server")
+	}
+}
+
+func TestDrawingUpdate(t *testing.T) {
+	d := resetTestDrawing(t)
+
+	d.X = 123
+	d.Locked = true
+	err := d.Update()
+
+	d.Read()
+	if err != nil {
+		t.Error("Could not update an existing drawing")
+	}
+	if d.X != 123 {
+		t.Error("This drawing seems to be misconfigured (X != 123)")
+	}
+	if d.Locked != true {
+		t.Error("This drawing seems to be misconfigured (locked != true)")
+	}
+}
+
+func TestDrawingDelete(t *testing.T) {
+	d := resetTestDrawing(t)
+
+	if err := d.Delete(); err != nil {
+		t.Error("Could not delete an existing drawing")
+		t.Error(err)
+	}
+}
+
+func TestDrawingDeleteError(t *testing.T) {
+	d := resetTestDrawing(t)
+	d.UUID = "11111111-1111-1111-1111-111111111111"
+
+	if err := d.Delete(); err != nil {
+		switch e := err.(type) {
+		case *ServerError:
+			if e.Status != 404 {
+				t.Error(e)
+			}
+		default:
+			t.Error(e)
+		}
+	}
+}
+
+func TestDrawingConnectionError(t *testing.T) {
+	p := &Project{Server: newTestUnreachableServer(), UUID: "1"}
+	d := Drawing{Project: p, UUID: "2"}
+
+	serverError := &ServerError{}
+	for _, err := range []error{d.Create(), d.Update(), d.Delete()} {
+		if err == nil || errors.As(err, &serverError) {
+			t.Errorf("A connection error must be returned as is (%v)", err)
+		}
+	}
+	if _, err := p.GetDrawings(); err == nil || errors.As(err, &serverError) {
+		t.Errorf("A connection error must be returned as is (%v)", err)
+	}
+}
@@ -0,0 +1,171 @@
+package gogns3
+
+import (
+	"fmt"
+	"path"
+	"regexp"
+	"strings"
+)
+
+// AmbiguousError is returned when an object is read by name and several
+// objects have this name
+type AmbiguousError struct {
+	Kind  string
+	Name  string
+	UUIDs []string
+}
+
+func (e *AmbiguousError) Error() string {
+	return fmt.Sprintf("ambiguous %s name %q (%d matches: %s)", e.Kind, e.Name, len(e.UUIDs), strings.Join(e.UUIDs, ", "))
+}
+
+// NameMatcher selects a project or a node on its name
+type NameMatcher func(name string) bool
+
+// Exact returns a name matcher for one name
+func Exact(name string) NameMatcher {
+	return func(n string) bool {
+		return n == name
+	}
+}
+
+// Glob returns a name matcher for a shell pattern, e.g. "PC*", with the syntax
+// of path.Match. A malformed pattern matches no name.
+func Glob(pattern string) NameMatcher {
+	return func(name string) bool {
+		matched, _ := path.Match(pattern, name)
+		return matched
+	}
+}
+
+// Regexp returns a name matcher for a regular expression
+func Regexp(re *regexp.Regexp) NameMatcher {
+	return re.MatchString
+}
+
+// ProjectName selects the projects whose name matches
+func ProjectName(m NameMatcher) func(Project) bool {
+	return func(p Project) bool {
+		return m(p.Name)
+	}
+}
+
+// ProjectStatus selects the projects with a status, e.g. "opened"
+func ProjectStatus(status string) func(Project) bool {
+	return func(p Project) bool {
+		return p.Status == status
+	}
+}
+
+// NodeName selects the nodes whose name matches
+func NodeName(m NameMatcher) func(Node) bool {
+	return func(n Node) bool {
+		return m(n.Name)
+	}
+}
+
+// NodeType selects the nodes of a type, e.g. "vpcs"
+func NodeType(nodeType string) func(Node) bool {
+	return func(n Node) bool {
+		return n.NodeType == nodeType
+	}
+}
+
+// NodeStatus selects the nodes with a status, e.g. "started"
+func NodeStatus(status string) func(Node) bool {
+	return func(n Node) bool {
+		return n.Status == status
+	}
+}
+
+// NodeCompute selects the nodes running on a compute
+func NodeCompute(computeID string) func(Node) bool {
+	return func(n Node) bool {
+		return n.ComputeID == computeID
+	}
+}
+
+// NodeConnectedTo selects the nodes connected by one of the links, usually
+// given by GetLinks(), to the node with the given UUID
+func NodeConnectedTo(links []Link, nodeID string) func(Node) bool {
+	neighbours := map[string]bool{}
+	for _, l := range links {
+		if LinkConnects(nodeID)(l) {
+			for _, end := range l.Nodes {
+				if end.NodeID != nodeID {
+					neighbours[end.NodeID] = true
+				}
+			}
+		}
+	}
+	return func(n Node) bool {
+		return neighbours[n.UUID]
+	}
+}
+
+// LinkConnects selects the links with an end on the node with the given UUID
+func LinkConnects(nodeID string) func(Link) bool {
+	return func(l Link) bool {
+		for _, end := range l.Nodes {
+			if end.NodeID == nodeID {
+				return true
+			}
+		}
+		return false
+	}
+}
+
+// FindProjects gets the projects of the server selected by all the filters
+func (s *Server) FindProjects(filters ...func(Project) bool) ([]Project, error) {
+	all, err := s.GetProjects()
+	if err != nil {
+		return nil, err
+	}
+	projects := []Project{}
+	for _, p := range all {
+		if matchesAll(len(filters), func(idx int) bool { return filters[idx](p) }) {
+			projects = append(projects, p)
+		}
+	}
+	return projects, nil
+}
+
+// FindNodes gets the nodes of the project selected by all the filters
+func (p *Project) FindNodes(filters ...func(Node) bool) ([]Node, error) {
+	all, err := p.GetNodes()
+	if err != nil {
+		return nil, err
+	}
+	nodes := []Node{}
+	for _, n := range all {
+		if matchesAll(len(filters), func(idx int) bool { return filters[idx](n) }) {
+			nodes = append(nodes, n)
+		}
+	}
+	return nodes, nil
+}
+
+// FindLinks gets the links of the project selected by all the filters
+func (p *Project) FindLinks(filters ...func(Link) bool) ([]Link, error) {
+	all, err := p.GetLinks()
+	if err != nil {
+		return nil, err
+	}
+	links := []Link{}
+	for _, l := range all {
+		if matchesAll(len(filters), func(idx int) bool { return filters[idx](l) }) {
+			links = append(links, l)
+		}
+	}
+	return links, nil
+}
+
+// matchesAll checks the n filters, called through match, all select an object
+func matchesAll(n int, match func(idx int) bool) bool {
+	for idx := 0; idx < n; idx++ {
+		if !match(idx) {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,177 @@
+package gogns3
+
+import (
+	"errors"
+	"net/http"
+	"regexp"
+	"testing"
+)
+
+func newTestFindServer() (*Server, func()) {
+	s, stop := newTestHTTPServer(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/v2/projects":
+			w.Write([]byte(`[
+				{"name": "lab1", "project_id": "p1", "status": "opened"},
+				{"name": "lab2", "project_id": "p2", "status": "closed"},
+				{"name": "lab2", "project_id": "p3", "status": "closed"}
+			]`))
+		case "/v2/projects/p1/nodes":
+			w.Write([]byte(`[
+				{"name": "PC1", "node_id": "n1", "node_type": "vpcs", "compute_id": "local", "status": "started"},
+				{"name": "PC2", "node_id": "n2", "node_type": "vpcs", "compute_id": "vm", "status": "stopped"},
+				{"name": "SW1", "node_id": "n3", "node_type": "ethernet_switch", "compute_id": "local", "status": "started"},
+				{"name": "SW1", "node_id": "n4", "node_type": "ethernet_switch", "compute_id": "local", "status": "started"}
+			]`))
+		case "/v2/projects/p1/links":
+			w.Write([]byte(`[
+				{"link_id": "l1", "nodes": [{"node_id": "n1", "adapter_number": 0, "port_number": 0}, {"node_id": "n3", "adapter_number": 0, "port_number": 0}]},
+				{"link_id": "l2", "nodes": [{"node_id": "n2", "adapter_number": 0, "port_number": 0}, {"node_id": "n3", "adapter_number": 0, "port_number": 1}]}
+			]`))
+		default:
+			http.NotFound(w, r)
+		}
+	})
+	s.APIVersion = APIv2
+	return s, stop
+}
+
+func TestServerFindProjects(t *testing.T) {
+	s, stop := newTestFindServer()
+	defer stop()
+
+	projects, err := s.FindProjects(ProjectName(Glob("lab*")), ProjectStatus("closed"))
+	if err != nil || len(projects) != 2 || projects[0].UUID != "p2" || projects[0].Server != s {
+		t.Errorf("These projects seem to be misselected (%+v, %v)", projects, err)
+	}
+	if projects, _ := s.FindProjects(); len(projects) != 3 {
+		t.Errorf("All the projects must be selected without filter (%d)", len(projects))
+	}
+
+	p := Project{Name: "lab2", Server: s}
+	ambiguousError := &AmbiguousError{}
+	if err := p.Read(); !errors.As(err, &ambiguousError) || len(ambiguousError.UUIDs) != 2 {
+		t.Errorf("Reading a duplicate project name must be ambiguous (%v)", err)
+	}
+	p = Project{Name: "lab1", Server: s}
+	if err := p.Read(); err != nil || p.UUID != "p1" {
+		t.Errorf("This project seems to be misread (%+v, %v)", p, err)
+	}
+}
+
+func TestProjectFindNodes(t *testing.T) {
+	s, stop := newTestFindServer()
+	defer stop()
+	p := &Project{Name: "lab1", Server: s, UUID: "p1"}
+	links, _ := p.GetLinks()
+
+	cases := []struct {
+		filters  []func(Node) bool
+		expected []string
+	}{
+		{[]func(Node) bool{NodeName(Regexp(regexp.MustCompile(`^PC\d$`)))}, []string{"n1", "n2"}},
+		{[]func(Node) bool{NodeType("ethernet_switch"), NodeName(Glob("SW*"))}, []string{"n3", "n4"}},
+		{[]func(Node) bool{NodeStatus("started"), NodeCompute("local"), NodeType("vpcs")}, []string{"n1"}},
+		{[]func(Node) bool{NodeName(Glob("[")), NodeType("vpcs")}, []string{}},
+		{[]func(Node) bool{NodeConnectedTo(links, "n3")}, []string{"n1", "n2"}},
+	}
+
+	for _, c := range cases {
+		nodes, err := p.FindNodes(c.filters...)
+		uuids := []string{}
+		for _, n := range nodes {
+			uuids = append(uuids, n.UUID)
+		}
+		if err != nil || !stringsEqual(uuids, c.expected) {
+			t.Errorf("These nodes seem to be misselected (%v instead of %v, %v)", uuids, c.expected, err)
+		}
+	}
+
+	n := Node{Name: "SW1", Project: p}
+	ambiguousError := &AmbiguousError{}
+	if err := n.Read(); !errors.As(err, &ambiguousError) || ambiguousError.Error() != `ambiguous node name "SW1" (2 matches: n3, n4)` {
+		t.Errorf("Reading a duplicate node name must be ambiguous (%v)", err)
+	}
+}
+
+func TestProjectFindLinks(t *testing.T) {
+	s, stop := newTestFindServer()
+	defer stop()
+	p := &Project{Name: "lab1", Server: s, UUID: "p1"}
+
+	links, err := p.FindLinks(LinkConnects("n2"))
+	if err != nil || len(links) != 1 || links[0].UUID != "l2" || links[0].Project != p {
+		t.Errorf("These links seem to be misselected (%+v, %v)", links, err)
+	}
+	if links, _ := p.FindLinks(LinkConnects("n3")); len(links) != 2 {
+		t.Errorf("Both links must connect the switch (%d)", len(links))
+	}
+}
+
+func stringsEqual(a []string, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for idx := range a {
+		if a[idx] != b[idx] {
+			return false
+		}
+	}
+	return true
+}
+
+func TestFindErrors(t *testing.T) {
+	s, stop := newTestHTTPServer(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusUnauthorized)
+		w.Write([]byte(`{"message": "Unauthorized", "status": 401}`))
+	})
+	defer stop()
+	s.APIVersion = APIv2
+
+	// an error of the server must not be read as an empty list
+	serverError := &ServerError{}
+	p := Project{Name: "lab1", Server: s}
+	if err := p.Read(); !errors.As(err, &serverError) || serverError.Status != 401 {
+		t.Errorf("Reading a project must fail with the server error (%v)", err)
+	}
+	p.UUID = "p1"
+	if _, err := p.FindNodes(); !errors.As(err, &serverError) || serverError.Status != 401 {
+		t.Errorf("Finding nodes must fail with the server error (%v)", err)
+	}
+
+	// neither must a connection error
+	p.Server = newTestUnreachableServer()
+	if _, err := p.FindLinks(); err == nil || errors.As(err, &serverError) {
+		t.Errorf("A connection error must be returned as is (%v)", err)
+	}
+	if _, err := p.Graph(); err == nil || errors.As(err, &serverError) {
+		t.Errorf("A connection error must be returned as is (%v)", err)
+	}
+}
+
+func TestAmbiguousActions(t *testing.T) {
+	s, stop := newTestFindServer()
+	defer stop()
+
+	// the objects are read by name first, which is ambiguous: nothing must be
+	// sent to an URL without UUID
+	ambiguousError := &AmbiguousError{}
+	p := &Project{Name: "lab2", Server: s}
+	if err := p.Open(); !errors.As(err, &ambiguousError) {
+		t.Errorf("Opening a duplicate project name must be ambiguous (%v)", err)
+	}
+	if err := p.Close(); !errors.As(err, &ambiguousError) {
+		t.Errorf("Closing a duplicate project name must be ambiguous (%v)", err)
+	}
+	if err := p.Delete(); !errors.As(err, &ambiguousError) {
+		t.Errorf("Deleting a duplicate project name must be ambiguous (%v)", err)
+	}
+
+	n := &Node{Name: "SW1", Project: &Project{Server: s, UUID: "p1"}}
+	if err := n.Start(); !errors.As(err, &ambiguousError) {
+		t.Errorf("Starting a duplicate node name must be ambiguous (%v)", err)
+	}
+	if err := n.Delete(); !errors.As(err, &ambiguousError) {
+		t.Errorf("Deleting a duplicate node name must be ambiguous (%v)", err)
+	}
+}
@@ -0,0 +1,300 @@
+// Package gns3file reads and writes GNS3 project files (.gns3) without a GNS3
+// server. The project, nodes, links and drawings are decoded into the
+// structures of the gogns3 package.
+//
+// The structures of the gogns3 package do not hold every field of the file
+// format, e.g. the properties of the node types it does not support. The
+// fields they do not hold are kept as read and written back as is, so that
+// reading and writing a file does not lose information.
+package gns3file
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/json"
+	"io"
+	"io/ioutil"
+	"os"
+
+	gogns3 "github.com/desnoe/go-gns3"
+)
+
+// Default values of the file header, as written by GNS3 2.2
+const (
+	DefaultRevision = 9
+	DefaultType     = "topology"
+	DefaultVersion  = "2.2.0"
+)
+
+// File is the content of a .gns3 project file
+type File struct {
+	Project  gogns3.Project
+	Revision int
+	Type     string
+	Version  string
+	Computes []Compute
+	Drawings []gogns3.Drawing
+	Links    []gogns3.Link
+	Nodes    []gogns3.Node
+
+	// fields read from the file which are not held by the structures above
+	extra         map[string]json.RawMessage
+	extraDrawings map[string]map[string]json.RawMessage
+	extraLinks    map[string]map[string]json.RawMessage
+	extraNodes    map[string]map[string]json.RawMessage
+}
+
+// Compute is a compute declared in a project file
+type Compute struct {
+	ComputeID string `json:"compute_id"`
+	Host      string `json:"host"`
+	Name      string `json:"name"`
+	Port      int    `json:"port"`
+	Protocol  string `json:"protocol"`
+}
+
+// file is the JSON layout of a project file, apart from the project fields
+type file struct {
+	Revision int      `json:"revision"`
+	Topology topology `json:"topology"`
+	Type     string   `json:"type"`
+	Version  string   `json:"version"`
+}
+
+type topology struct {
+	Computes []Compute                    `json:"computes"`
+	Drawings []map[string]json.RawMessage `json:"drawings"`
+	Links    []map[string]json.RawMessage `json:"links"`
+	Nodes    []map[string]json.RawMessage `json:"nodes"`
+}
+
+// New returns an empty project file with the default header
+func New(name string) *File {
+	return &File{
+		Project:  gogns3.Project{Name: name},
+		Revision: DefaultRevision,
+		Type:     DefaultType,
+		Version:  DefaultVersion,
+	}
+}
+
+// Read reads a project file
+func Read(r io.Reader) (*File, error) {
+	content, err := ioutil.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+
+	f := &File{
+		extraDrawings: map[string]map[string]json.RawMessage{},
+		extraLinks:    map[string]map[string]json.RawMessage{},
+		extraNodes:    map[string]map[string]json.RawMessage{},
+	}
+	raw := file{}
+	if err := json.Unmarshal(content, &raw); err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(content, &f.Project); err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(content, &f.extra); err != nil {
+		return nil, err
+	}
+	f.Revision, f.Type, f.Version = raw.Revision, raw.Type, raw.Version
+	f.Computes = raw.Topology.Computes
+
+	for _, fields := range raw.Topology.Nodes {
+		n := gogns3.Node{}
+		if err := decode(fields, &n); err != nil {
+			return nil, err
+		}
+		f.extraNodes[n.UUID] = fields
+		f.Nodes = append(f.Nodes, n)
+	}
+	for _, fields := range raw.Topology.Links {
+		l := gogns3.Link{}
+		if err := decode(fields, &l); err != nil {
+			return nil, err
+		}
+		f.extraLinks[l.UUID] = fields
+		f.Links = append(f.Links, l)
+	}
+	for _, fields := range raw.Topology.Drawings {
+		d := gogns3.Drawing{}
+		if err := decode(fields, &d); err != nil {
+			return nil, err
+		}
+		f.extraDrawings[d.UUID] = fields
+		f.Drawings = append(f.Drawings, d)
+	}
+	f.setProject()
+	return f, nil
+}
+
+// ReadFile reads a project file from the disk
+func ReadFile(path string) (*File, error) {
+	r, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer r.Close()
+	return Read(r)
+}
+
+// Write writes the project file
+func (f *File) Write(w io.Writer) error {
+	document, err := merge(f.extra, f.Project)
+	if err != nil {
+		return err
+	}
+	header, err := json.Marshal(file{
+		Revision: f.Revision,
+		Type:     f.Type,
+		Version:  f.Version,
+	})
+	if err != nil {
+		return err
+	}
+	if err := json.Unmarshal(header, &document); err != nil {
+		return err
+	}
+
+	t := topology{
+		Computes: f.Computes,
+		Drawings: []map[string]json.RawMessage{},
+		Links:    []map[string]json.RawMessage{},
+		Nodes:    []map[string]json.RawMessage{},
+	}
+	if t.Computes == nil {
+		t.Computes = []Compute{}
+	}
+	for _, n := range f.Nodes {
+		fields, err := merge(f.extraNodes[n.UUID], n)
+		if err != nil {
+			return err
+		}
+		t.Nodes = append(t.Nodes, fields)
+	}
+	for _, l := range f.Links {
+		fields, err := merge(f.extraLinks[l.UUID], l)
+		if err != nil {
+			return err
+		}
+		t.Links = append(t.Links, fields)
+	}
+	for _, d := range f.Drawings {
+		fields, err := merge(f.extraDrawings[d.UUID], d)
+		if err != nil {
+			return err
+		}
+		t.Drawings = append(t.Drawings, fields)
+	}
+	if document["topology"], err = json.Marshal(t); err != nil {
+		return err
+	}
+
+	b, err := json.MarshalIndent(document, "", "    ")
+	if err != nil {
+		return err
+	}
+	_, err = w.Write(append(b, '\n'))
+	return err
+}
+
+// WriteFile writes the project file on the disk
+func (f *File) WriteFile(path string) error {
+	b := &bytes.Buffer{}
+	if err := f.Write(b); err != nil {
+		return err
+	}
+	return ioutil.WriteFile(path, b.Bytes(), 0644)
+}
+
+// WriteArchive writes a portable project, i.e. a zip archive containing the
+// project file, which can be imported on a server
+func (f *File) WriteArchive(w io.Writer) error {
+	archive := zip.NewWriter(w)
+	fw, err := archive.Create("project.gns3")
+	if err != nil {
+		return err
+	}
+	if err := f.Write(fw); err != nil {
+		return err
+	}
+	return archive.Close()
+}
+
+// Import imports the project file as a new project on the server, under the
+// given name
+func (f *File) Import(s *gogns3.Server, name string) (*gogns3.Project, error) {
+	b := &bytes.Buffer{}
+	if err := f.WriteArchive(b); err != nil {
+		return nil, err
+	}
+	p := &gogns3.Project{
+		Name:   name,
+		Server: s,
+	}
+	return p, p.Import(b)
+}
+
+// setProject sets the project of the nodes, links and drawings
+func (f *File) setProject() {
+	for idx := range f.Nodes {
+		f.Nodes[idx].Project = &f.Project
+	}
+	for idx := range f.Links {
+		f.Links[idx].Project = &f.Project
+	}
+	for idx := range f.Drawings {
+		f.Drawings[idx].Project = &f.Project
+	}
+}
+
+// decode decodes the fields of an object of the file into a structure
+func decode(fields map[string]json.RawMessage, v interface{}) error {
+	b, err := json.Marshal(fields)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(b, v)
+}
+
+// merge encodes the structure and merges its fields over the fields read from
+// the file, see mergeValue
+func merge(fields map[string]json.RawMessage, v interface{}) (map[string]json.RawMessage, error) {
+	b, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	if fields != nil {
+		original, _ := json.Marshal(fields)
+		b = mergeValue(original, b)
+	}
+	merged := map[string]json.RawMessage{}
+	err = json.Unmarshal(b, &merged)
+	return merged, err
+}
+
+// mergeValue merges an encoded value over the value read from the file. Objects
+// are merged field by field, so that the fields the structures cannot hold are
+// kept. Null values do not overwrite the values read, they are the ones the
+// structures cannot represent (e.g. unsupported node properties).
+func mergeValue(original json.RawMessage, encoded json.RawMessage) json.RawMessage {
+	if string(encoded) == "null" {
+		return original
+	}
+	originalFields := map[string]json.RawMessage{}
+	encodedFields := map[string]json.RawMessage{}
+	if json.Unmarshal(original, &originalFields) != nil || json.Unmarshal(encoded, &encodedFields) != nil {
+		return encoded
+	}
+	for key, value := range encodedFields {
+		if o, ok := originalFields[key]; ok {
+			value = mergeValue(o, value)
+		}
+		originalFields[key] = value
+	}
+	b, _ := json.Marshal(originalFields)
+	return b
+}
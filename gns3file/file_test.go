@@ -0,0 +1,181 @@
+package gns3file
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/json"
+	"io/ioutil"
+	"strings"
+	"testing"
+)
+
+const testFile = `{
+    "auto_close": true,
+    "auto_open": false,
+    "auto_start": false,
+    "name": "lab",
+    "project_id": "0a1b2c3d-0000-4000-8000-000000000000",
+    "revision": 9,
+    "scene_height": 1000,
+    "scene_width": 2000,
+    "show_grid": false,
+    "show_interface_labels": false,
+    "show_layers": false,
+    "snap_to_grid": false,
+    "supplier": null,
+    "topology": {
+        "computes": [],
+        "drawings": [
+            {
+                "drawing_id": "d0000000-0000-4000-8000-000000000000",
+                "locked": false,
+                "rotation": 0,
+                "svg": "<svg height=\"50\" width=\"100\"><rect height=\"50\" width=\"100\" /></svg>",
+                "x": 10,
+                "y": 20,
+                "z": 1
+            }
+        ],
+        "links": [
+            {
+                "filters": {"packet_loss": [10]},
+                "link_id": "10000000-0000-4000-8000-000000000000",
+                "link_style": {},
+                "nodes": [
+                    {"adapter_number": 0, "node_id": "a0000000-0000-4000-8000-000000000000", "port_number": 0},
+                    {"adapter_number": 0, "node_id": "b0000000-0000-4000-8000-000000000000", "port_number": 0}
+                ],
+                "suspend": false
+            }
+        ],
+        "nodes": [
+            {
+                "compute_id": "local",
+                "console": 5000,
+                "console_auto_start": false,
+                "console_type": "telnet",
+                "name": "R1",
+                "node_id": "a0000000-0000-4000-8000-000000000000",
+                "node_type": "dynamips",
+                "properties": {"dynamips_id": 1, "image": "c7200-adventerprisek9-mz.124-24.T5.image", "platform": "c7200", "ram": 512},
+                "symbol": ":/symbols/router.svg",
+                "x": -100,
+                "y": 0,
+                "z": 1
+            },
+            {
+                "compute_id": "local",
+                "console": 5001,
+                "console_type": "telnet",
+                "name": "PC1",
+                "node_id": "b0000000-0000-4000-8000-000000000000",
+                "node_type": "vpcs",
+                "properties": {},
+                "symbol": ":/symbols/vpcs_guest.svg",
+                "x": 100,
+                "y": 0,
+                "z": 1
+            }
+        ]
+    },
+    "type": "topology",
+    "variables": null,
+    "version": "2.2.17",
+    "zoom": 100
+}`
+
+func TestRead(t *testing.T) {
+	f, err := Read(strings.NewReader(testFile))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if f.Project.Name != "lab" || f.Project.SceneWidth != 2000 || f.Project.AutoClose != true {
+		t.Errorf("This project seems to be misread (%+v)", f.Project)
+	}
+	if f.Revision != 9 || f.Type != "topology" || f.Version != "2.2.17" {
+		t.Error("This file header seems to be misread")
+	}
+	if len(f.Nodes) != 2 || f.Nodes[0].Name != "R1" || f.Nodes[0].Properties.DynamipsID != 1 {
+		t.Error("These nodes seem to be misread")
+	}
+	if f.Nodes[1].Project != &f.Project {
+		t.Error("These nodes must belong to the project of the file")
+	}
+	if len(f.Links) != 1 || f.Links[0].Nodes[1].NodeID != f.Nodes[1].UUID {
+		t.Error("These links seem to be misread")
+	}
+	if f.Links[0].Filters == nil || *f.Links[0].Filters.PacketLoss != 10 {
+		t.Error("These links seem to be misread (packet_loss != 10)")
+	}
+	if len(f.Drawings) != 1 || f.Drawings[0].X != 10 {
+		t.Error("These drawings seem to be misread")
+	}
+}
+
+func TestWrite(t *testing.T) {
+	f, err := Read(strings.NewReader(testFile))
+	if err != nil {
+		t.Fatal(err)
+	}
+	f.Nodes[0].X = -200
+	f.Nodes[1].Name = "PC2"
+	f.Project.Zoom = 150
+
+	b := &bytes.Buffer{}
+	if err := f.Write(b); err != nil {
+		t.Fatal(err)
+	}
+	document := map[string]interface{}{}
+	if err := json.Unmarshal(b.Bytes(), &document); err != nil {
+		t.Fatal(err)
+	}
+	if document["zoom"] != 150.0 || document["auto_open"] != false || document["version"] != "2.2.17" {
+		t.Errorf("This file seems to be miswritten:\n%s", b)
+	}
+	nodes := document["topology"].(map[string]interface{})["nodes"].([]interface{})
+	r1 := nodes[0].(map[string]interface{})
+	if r1["x"] != -200.0 || r1["console_auto_start"] != false {
+		t.Errorf("This node seems to be miswritten (%v)", r1)
+	}
+	if properties := r1["properties"].(map[string]interface{}); properties["image"] != "c7200-adventerprisek9-mz.124-24.T5.image" {
+		t.Errorf("The unsupported properties must be kept (%v)", properties)
+	}
+	if nodes[1].(map[string]interface{})["name"] != "PC2" {
+		t.Error("This node seems to be miswritten (name != PC2)")
+	}
+
+	f, err = Read(b)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(f.Nodes) != 2 || len(f.Links) != 1 || len(f.Drawings) != 1 {
+		t.Error("This file must be read back")
+	}
+}
+
+func TestNewWriteArchive(t *testing.T) {
+	f := New("lab")
+
+	b := &bytes.Buffer{}
+	if err := f.WriteArchive(b); err != nil {
+		t.Fatal(err)
+	}
+	archive, err := zip.NewReader(bytes.NewReader(b.Bytes()), int64(b.Len()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(archive.File) != 1 || archive.File[0].Name != "project.gns3" {
+		t.Fatal("The archive must contain a project.gns3 file")
+	}
+	r, _ := archive.File[0].Open()
+	content, _ := ioutil.ReadAll(r)
+
+	f, err = Read(bytes.NewReader(content))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if f.Project.Name != "lab" || f.Revision != DefaultRevision || f.Type != DefaultType {
+		t.Errorf("This file seems to be miswritten:\n%s", content)
+	}
+}
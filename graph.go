@@ -0,0 +1,165 @@
+package gogns3
+
+import "sort"
+
+// Graph is an in-memory graph of the nodes and links of a project. The nodes
+// are identified by their UUID. All the links are edges of the graph, the
+// suspended ones included.
+type Graph struct {
+	Links []Link
+	Nodes []Node
+
+	// edges of each node, by node UUID
+	edges map[string][]Edge
+	// index of each node in Nodes, by node UUID
+	index map[string]int
+}
+
+// Edge is the connection of a node to a neighbour through a link: Local is the
+// end of the link on the node and Remote the end on the neighbour
+type Edge struct {
+	Link   *Link
+	Local  LinkNode
+	Remote LinkNode
+}
+
+// Graph gets the nodes and links of the project and builds their graph
+func (p *Project) Graph() (*Graph, error) {
+	nodes, err := p.GetNodes()
+	if err != nil {
+		return nil, err
+	}
+	links, err := p.GetLinks()
+	if err != nil {
+		return nil, err
+	}
+	return NewGraph(nodes, links), nil
+}
+
+// NewGraph builds the graph of nodes and links. The links which do not have
+// two ends are ignored.
+func NewGraph(nodes []Node, links []Link) *Graph {
+	g := &Graph{
+		Links: links,
+		Nodes: nodes,
+		edges: map[string][]Edge{},
+		index: map[string]int{},
+	}
+	for idx, n := range nodes {
+		g.index[n.UUID] = idx
+	}
+	for idx := range links {
+		l := &links[idx]
+		if len(l.Nodes) != 2 {
+			continue
+		}
+		a, b := l.Nodes[0], l.Nodes[1]
+		g.edges[a.NodeID] = append(g.edges[a.NodeID], Edge{Link: l, Local: a, Remote: b})
+		g.edges[b.NodeID] = append(g.edges[b.NodeID], Edge{Link: l, Local: b, Remote: a})
+	}
+	return g
+}
+
+// Node gets a node of the graph by UUID, or nil if there is none
+func (g *Graph) Node(nodeID string) *Node {
+	idx, ok := g.index[nodeID]
+	if !ok {
+		return nil
+	}
+	return &g.Nodes[idx]
+}
+
+// Edges gets the edges of a node, in the order of the links
+func (g *Graph) Edges(nodeID string) []Edge {
+	return g.edges[nodeID]
+}
+
+// Neighbours gets the nodes connected to a node by at least one link, in the
+// order of the links
+func (g *Graph) Neighbours(nodeID string) []Node {
+	neighbours := []Node{}
+	seen := map[string]bool{}
+	for _, e := range g.edges[nodeID] {
+		if n := g.Node(e.Remote.NodeID); n != nil && !seen[n.UUID] {
+			seen[n.UUID] = true
+			neighbours = append(neighbours, *n)
+		}
+	}
+	return neighbours
+}
+
+// Connections gets the edges connecting node a to node b. The adapter and port
+// of a are given by the Local end of each edge, those of b by the Remote end.
+func (g *Graph) Connections(a string, b string) []Edge {
+	edges := []Edge{}
+	for _, e := range g.edges[a] {
+		if e.Remote.NodeID == b {
+			edges = append(edges, e)
+		}
+	}
+	return edges
+}
+
+// ShortestPath gets the nodes on a shortest path between two nodes, both ends
+// included, or nil if they are not connected
+func (g *Graph) ShortestPath(from string, to string) []Node {
+	if g.Node(from) == nil || g.Node(to) == nil {
+		return nil
+	}
+
+	// breadth-first search from the first node, remembering the previous node
+	// of each node reached
+	previous := map[string]string{from: ""}
+	queue := []string{from}
+	for len(queue) != 0 && queue[0] != to {
+		current := queue[0]
+		queue = queue[1:]
+		for _, n := range g.Neighbours(current) {
+			if _, ok := previous[n.UUID]; !ok {
+				previous[n.UUID] = current
+				queue = append(queue, n.UUID)
+			}
+		}
+	}
+	if _, ok := previous[to]; !ok {
+		return nil
+	}
+
+	path := []Node{}
+	for id := to; id != ""; id = previous[id] {
+		path = append([]Node{*g.Node(id)}, path...)
+	}
+	return path
+}
+
+// Components gets the connected components of the graph, i.e. the groups of
+// nodes connected to each other. The components and their nodes are given in
+// the order of the nodes of the graph.
+func (g *Graph) Components() [][]Node {
+	components := [][]Node{}
+	seen := map[string]bool{}
+	for _, n := range g.Nodes {
+		if seen[n.UUID] {
+			continue
+		}
+		seen[n.UUID] = true
+		members := []int{g.index[n.UUID]}
+		for queue := []string{n.UUID}; len(queue) != 0; queue = queue[1:] {
+			for _, neighbour := range g.Neighbours(queue[0]) {
+				if !seen[neighbour.UUID] {
+					seen[neighbour.UUID] = true
+					members = append(members, g.index[neighbour.UUID])
+					queue = append(queue, neighbour.UUID)
+				}
+			}
+		}
+
+		sort.Ints(members)
+		component := []Node{}
+		for _, idx := range members {
+			component = append(component, g.Nodes[idx])
+		}
+		components = append(components, component)
+	}
+	return components
+}
@@ -0,0 +1,102 @@
+package gogns3
+
+import "testing"
+
+// newTestGraph returns the graph of PC1 - SW1 = R1 - PC2, with a double link
+// between SW1 and R1, and of PC3 alone
+func newTestGraph() *Graph {
+	nodes := []Node{{Name: "PC1", UUID: "pc1"}, {Name: "PC2", UUID: "pc2"}, {Name: "PC3", UUID: "pc3"}, {Name: "R1", UUID: "r1"}, {Name: "SW1", UUID: "sw1"}}
+	links := []Link{
+		{UUID: "l1", Nodes: []LinkNode{{NodeID: "pc1"}, {NodeID: "sw1", PortNumber: 1}}},
+		{UUID: "l2", Nodes: []LinkNode{{NodeID: "sw1", PortNumber: 2}, {NodeID: "r1", AdapterNumber: 0}}},
+		{UUID: "l3", Nodes: []LinkNode{{NodeID: "sw1", PortNumber: 3}, {NodeID: "r1", AdapterNumber: 1}}},
+		{UUID: "l4", Nodes: []LinkNode{{NodeID: "r1", AdapterNumber: 2}, {NodeID: "pc2"}}},
+		{UUID: "l5", Nodes: []LinkNode{{NodeID: "pc3"}}},
+	}
+	return NewGraph(nodes, links)
+}
+
+func nodeNames(nodes []Node) []string {
+	names := []string{}
+	for _, n := range nodes {
+		names = append(names, n.Name)
+	}
+	return names
+}
+
+func TestGraphNeighbours(t *testing.T) {
+	g := newTestGraph()
+
+	if names := nodeNames(g.Neighbours("sw1")); !stringsEqual(names, []string{"PC1", "R1"}) {
+		t.Errorf("These neighbours seem to be wrong (%v)", names)
+	}
+	if names := nodeNames(g.Neighbours("pc3")); len(names) != 0 {
+		t.Errorf("A node without link must have no neighbour (%v)", names)
+	}
+	if len(g.Edges("r1")) != 3 {
+		t.Errorf("R1 must have 3 edges (%d)", len(g.Edges("r1")))
+	}
+}
+
+func TestGraphConnections(t *testing.T) {
+	g := newTestGraph()
+
+	edges := g.Connections("r1", "sw1")
+	if len(edges) != 2 || edges[0].Local.AdapterNumber != 0 || edges[1].Local.AdapterNumber != 1 || edges[1].Remote.PortNumber != 3 || edges[1].Link.UUID != "l3" {
+		t.Errorf("These connections seem to be wrong (%+v)", edges)
+	}
+	if edges := g.Connections("pc1", "pc2"); len(edges) != 0 {
+		t.Errorf("PC1 and PC2 must not be directly connected (%+v)", edges)
+	}
+}
+
+func TestGraphShortestPath(t *testing.T) {
+	g := newTestGraph()
+
+	cases := []struct {
+		from     string
+		to       string
+		expected []string
+	}{
+		{"pc1", "pc2", []string{"PC1", "SW1", "R1", "PC2"}},
+		{"pc2", "sw1", []string{"PC2", "R1", "SW1"}},
+		{"pc1", "pc1", []string{"PC1"}},
+		{"pc1", "pc3", nil},
+		{"pc1", "unknown", nil},
+	}
+	for _, c := range cases {
+		path := g.ShortestPath(c.from, c.to)
+		if (path == nil) != (c.expected == nil) || !stringsEqual(nodeNames(path), c.expected) {
+			t.Errorf("This path from %s to %s seems to be wrong (%v)", c.from, c.to, nodeNames(path))
+		}
+	}
+}
+
+func TestGraphComponents(t *testing.T) {
+	g := newTestGraph()
+
+	components := g.Components()
+	if len(components) != 2 || !stringsEqual(nodeNames(components[0]), []string{"PC1", "PC2", "R1", "SW1"}) || !stringsEqual(nodeNames(components[1]), []string{"PC3"}) {
+		t.Errorf("These components seem to be wrong (%v)", components)
+	}
+}
+
+func TestProjectGraph(t *testing.T) {
+	s, stop := newTestFindServer()
+	defer stop()
+	p := &Project{Name: "lab1", Server: s, UUID: "p1"}
+
+	g, err := p.Graph()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if names := nodeNames(g.ShortestPath("n1", "n2")); !stringsEqual(names, []string{"PC1", "SW1", "PC2"}) {
+		t.Errorf("This path seems to be wrong (%v)", names)
+	}
+	if edges := g.Connections("n3", "n2"); len(edges) != 1 || edges[0].Local.PortNumber != 1 {
+		t.Errorf("SW1 must be connected to PC2 on port 1 (%+v)", edges)
+	}
+	if g.Node("n4") == nil || len(g.Components()) != 2 {
+		t.Error("The second switch must be alone")
+	}
+}
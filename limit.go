@@ -0,0 +1,98 @@
+package gogns3
+
+import (
+	"context"
+	"math"
+	"sync"
+	"time"
+)
+
+// requestLimits are the rate limiter and the in-flight semaphore of a server
+type requestLimits struct {
+	inFlight chan struct{}
+	rate     *rateLimiter
+}
+
+// rateLimiter is a token bucket: it holds up to burst tokens, refilled at rate
+// tokens per second, and each request takes one token
+type rateLimiter struct {
+	mu     sync.Mutex
+	burst  float64
+	last   time.Time
+	rate   float64
+	tokens float64
+}
+
+func newRateLimiter(rate float64, burst int) *rateLimiter {
+	if burst < 1 {
+		burst = 1
+	}
+	return &rateLimiter{
+		burst:  float64(burst),
+		last:   time.Now(),
+		rate:   rate,
+		tokens: float64(burst),
+	}
+}
+
+// wait takes a token, waiting for the bucket to be refilled if it is empty
+func (l *rateLimiter) wait(ctx context.Context) error {
+	l.mu.Lock()
+	now := time.Now()
+	l.tokens = math.Min(l.burst, l.tokens+now.Sub(l.last).Seconds()*l.rate)
+	l.last = now
+	// the token is taken now, the bucket may go negative: the next requests
+	// then wait for the previous ones to be served
+	l.tokens--
+	delay := time.Duration(-l.tokens / l.rate * float64(time.Second))
+	l.mu.Unlock()
+
+	if delay <= 0 {
+		return nil
+	}
+	if err := sleepContext(ctx, delay); err != nil {
+		// give the token back as the request will not be sent
+		l.mu.Lock()
+		l.tokens++
+		l.mu.Unlock()
+		return err
+	}
+	return nil
+}
+
+// limits returns the request limits of the server, built from its settings on
+// the first request
+func (s *Server) limits() *requestLimits {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.requestLimits == nil {
+		s.requestLimits = &requestLimits{}
+		if s.MaxInFlight > 0 {
+			s.requestLimits.inFlight = make(chan struct{}, s.MaxInFlight)
+		}
+		if s.RateLimit > 0 {
+			s.requestLimits.rate = newRateLimiter(s.RateLimit, s.RateBurst)
+		}
+	}
+	return s.requestLimits
+}
+
+// acquire waits until a request can be sent to the server. The returned
+// function must be called once the request is done.
+func (s *Server) acquire(ctx context.Context) (func(), error) {
+	limits := s.limits()
+	if limits.rate != nil {
+		if err := limits.rate.wait(ctx); err != nil {
+			return nil, err
+		}
+	}
+	if limits.inFlight == nil {
+		return func() {}, nil
+	}
+	select {
+	case limits.inFlight <- struct{}{}:
+		return func() { <-limits.inFlight }, nil
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
@@ -0,0 +1,125 @@
+package gogns3
+
+import (
+	"context"
+	"net/http"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestServerMaxInFlight(t *testing.T) {
+	mu := sync.Mutex{}
+	inFlight, maxInFlight := 0, 0
+	s, stop := newTestHTTPServer(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		inFlight++
+		if inFlight > maxInFlight {
+			maxInFlight = inFlight
+		}
+		mu.Unlock()
+		time.Sleep(10 * time.Millisecond)
+		mu.Lock()
+		inFlight--
+		mu.Unlock()
+	})
+	defer stop()
+	s.APIVersion, s.MaxInFlight = APIv2, 3
+
+	wg := sync.WaitGroup{}
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if _, _, err := s.HTTPRequest("GET", s.url(), nil); err != nil {
+				t.Error(err)
+			}
+		}()
+	}
+	wg.Wait()
+
+	if maxInFlight != 3 {
+		t.Errorf("The requests in flight must be limited to 3 (%d)", maxInFlight)
+	}
+}
+
+func TestServerNegotiationLimits(t *testing.T) {
+	mu := sync.Mutex{}
+	inFlight, maxInFlight, negotiations := 0, 0, 0
+	s, stop := newTestHTTPServer(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		inFlight++
+		if inFlight > maxInFlight {
+			maxInFlight = inFlight
+		}
+		if r.URL.Path == "/v3/version" {
+			negotiations++
+		}
+		mu.Unlock()
+		time.Sleep(10 * time.Millisecond)
+		mu.Lock()
+		inFlight--
+		mu.Unlock()
+		if r.URL.Path == "/v3/version" {
+			http.NotFound(w, r)
+			return
+		}
+		w.Write([]byte("[]"))
+	})
+	defer stop()
+	s.MaxInFlight = 1
+
+	// the negotiation is done once, within the limit of the requests in
+	// flight
+	wg := sync.WaitGroup{}
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if _, err := s.GetProjects(); err != nil {
+				t.Error(err)
+			}
+		}()
+	}
+	wg.Wait()
+
+	if negotiations != 1 || maxInFlight != 1 {
+		t.Errorf("The API version must be negotiated once, one request at a time (%d negotiations, %d in flight)", negotiations, maxInFlight)
+	}
+}
+
+func TestServerRateLimit(t *testing.T) {
+	s, stop := newTestHTTPServer(func(w http.ResponseWriter, r *http.Request) {})
+	defer stop()
+	s.APIVersion, s.RateLimit, s.RateBurst = APIv2, 50, 2
+
+	start := time.Now()
+	wg := sync.WaitGroup{}
+	for i := 0; i < 7; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			s.HTTPRequest("GET", s.url(), nil)
+		}()
+	}
+	wg.Wait()
+
+	// 2 requests in the burst, then 5 requests at 50 per second
+	if elapsed := time.Since(start); elapsed < 95*time.Millisecond || elapsed > time.Second {
+		t.Errorf("The requests must be rate limited (%v)", elapsed)
+	}
+}
+
+func TestRateLimiterCancel(t *testing.T) {
+	l := newRateLimiter(1, 1)
+	l.wait(context.Background())
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if err := l.wait(ctx); err != context.DeadlineExceeded {
+		t.Errorf("The wait must end with the context (%v)", err)
+	}
+	if l.tokens < -0.1 {
+		t.Errorf("The token of a cancelled wait must be given back (%v)", l.tokens)
+	}
+}
@@ -1,17 +1,23 @@
 package gogns3
 
-import "encoding/json"
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"time"
+)
 
 // Link is the basic structure used for a GNS3 link
 type Link struct {
-	CaptureFileName string     `json:"capture_file_name,omitempty"`
-	CaptureFilePath string     `json:"capture_file_path,omitempty"`
-	Capturing       bool       `json:"capturing"`
-	LinkType        string     `json:"link_type,omitempty"`
-	Nodes           []LinkNode `json:"nodes,omitempty"`
-	Project         *Project   `json:"-"`
-	Suspend         bool       `json:"suspend"`
-	UUID            string     `json:"link_id,omitempty"`
+	CaptureFileName string       `json:"capture_file_name,omitempty"`
+	CaptureFilePath string       `json:"capture_file_path,omitempty"`
+	Capturing       bool         `json:"capturing"`
+	Filters         *LinkFilters `json:"filters,omitempty"`
+	LinkType        string       `json:"link_type,omitempty"`
+	Nodes           []LinkNode   `json:"nodes,omitempty"`
+	Project         *Project     `json:"-"`
+	Suspend         bool         `json:"suspend"`
+	UUID            string       `json:"link_id,omitempty"`
 }
 
 // LinkNode is the structure used to identify the end of a link
@@ -22,25 +28,148 @@ type LinkNode struct {
 	PortNumber    int    `json:"port_number"`
 }
 
-func (l *Link) url() string {
-	return l.Project.url() + "/links/" + l.UUID
+// LinkFilters are the impairments applied to the packets going through a link.
+// A nil field means the filter is not applied, an empty LinkFilters structure
+// removes all the filters of the link on update.
+type LinkFilters struct {
+	// FrequencyDrop drops every Nth packet, or every packet if set to -1
+	FrequencyDrop *int
+	// PacketLoss is the chance, in percent, that a packet is dropped
+	PacketLoss *int
+	// Delay delays every packet
+	Delay *LinkDelay
+	// Corrupt is the chance, in percent, that a packet is corrupted
+	Corrupt *int
+	// BPF drops the packets matching the Berkeley Packet Filter expressions,
+	// one per line
+	BPF string
 }
 
-// Read reads an existing node in the project
-func (l *Link) Read() error {
-	// save the project information as it will be reset
-	project := l.Project
-	links, _ := l.Project.GetLinks()
-	for _, link := range links {
-		// Links are not named in GNS3, so read will be based on UUID and not name
-		if link.UUID == l.UUID {
-			*l = link
-			// restore the project information
-			l.Project = project
-			return nil
+// LinkDelay is the delay filter of a link, in milliseconds
+type LinkDelay struct {
+	Latency int
+	Jitter  int
+}
+
+// LinkFilter describes a filter supported by a link
+type LinkFilter struct {
+	Description string                `json:"description"`
+	Name        string                `json:"name"`
+	Parameters  []LinkFilterParameter `json:"parameters"`
+	Type        string                `json:"type"`
+}
+
+// LinkFilterParameter describes a parameter of a link filter
+type LinkFilterParameter struct {
+	Maximum int    `json:"maximum,omitempty"`
+	Minimum int    `json:"minimum,omitempty"`
+	Name    string `json:"name"`
+	Type    string `json:"type"`
+	Unit    string `json:"unit,omitempty"`
+}
+
+// MarshalJSON converts the filters into the GNS3 server representation where
+// each filter is a list of values named after its type.
+func (f LinkFilters) MarshalJSON() ([]byte, error) {
+	filters := map[string][]interface{}{}
+	if f.FrequencyDrop != nil {
+		filters["frequency_drop"] = []interface{}{*f.FrequencyDrop}
+	}
+	if f.PacketLoss != nil {
+		filters["packet_loss"] = []interface{}{*f.PacketLoss}
+	}
+	if f.Delay != nil {
+		filters["delay"] = []interface{}{f.Delay.Latency, f.Delay.Jitter}
+	}
+	if f.Corrupt != nil {
+		filters["corrupt"] = []interface{}{*f.Corrupt}
+	}
+	if f.BPF != "" {
+		filters["bpf"] = []interface{}{f.BPF}
+	}
+	return json.Marshal(filters)
+}
+
+// UnmarshalJSON converts the GNS3 server representation of the filters.
+// Unknown filter types are ignored.
+func (f *LinkFilters) UnmarshalJSON(b []byte) error {
+	filters := map[string][]json.RawMessage{}
+	if err := json.Unmarshal(b, &filters); err != nil {
+		return err
+	}
+
+	*f = LinkFilters{}
+	intValue := func(values []json.RawMessage, idx int) (int, error) {
+		var value int
+		if idx >= len(values) {
+			return 0, nil
+		}
+		err := json.Unmarshal(values[idx], &value)
+		return value, err
+	}
+	for name, values := range filters {
+		if len(values) == 0 {
+			continue
+		}
+		switch name {
+		case "frequency_drop", "packet_loss", "corrupt":
+			value, err := intValue(values, 0)
+			if err != nil {
+				return err
+			}
+			switch name {
+			case "frequency_drop":
+				f.FrequencyDrop = &value
+			case "packet_loss":
+				f.PacketLoss = &value
+			case "corrupt":
+				f.Corrupt = &value
+			}
+		case "delay":
+			latency, err := intValue(values, 0)
+			if err != nil {
+				return err
+			}
+			jitter, err := intValue(values, 1)
+			if err != nil {
+				return err
+			}
+			f.Delay = &LinkDelay{Latency: latency, Jitter: jitter}
+		case "bpf":
+			if err := json.Unmarshal(values[0], &f.BPF); err != nil {
+				return err
+			}
 		}
 	}
-	return &ServerError{Status: 404, Message: "Link does not exist in the project"}
+	return nil
+}
+
+// url returns the URL of the link, or of the given path under it
+func (l *Link) url(elements ...string) string {
+	return l.Project.url(append([]string{"links", l.UUID}, elements...)...)
+}
+
+// Read reads an existing link in the project. Links are not named in GNS3, so
+// the link is read by UUID.
+func (l *Link) Read() error {
+	if l.UUID == "" {
+		return &ServerError{Status: 404, Message: "Link does not exist in the project"}
+	}
+	status, content, err := l.Project.Server.HTTPRequest("GET", l.url(), nil)
+	if err != nil {
+		return err
+	}
+	if !(status >= 200 && status < 300) {
+		serverError := ServerError{Status: status}
+		json.Unmarshal(content, &serverError)
+		return &serverError
+	}
+	link := Link{Project: l.Project}
+	if err := json.Unmarshal(content, &link); err != nil {
+		return err
+	}
+	*l = link
+	return nil
 }
 
 // Exists checks a link exists in the project
@@ -57,41 +186,209 @@ func (l *Link) Exists() (bool, error) {
 
 // Create creates a link in the project
 func (l *Link) Create() error {
+	if l.Filters != nil {
+		if err := l.Project.Server.require(FeatureLinkFilters); err != nil {
+			return err
+		}
+	}
 	b, _ := json.Marshal(l)
-	status, content, err := l.Project.Server.HTTPRequest("POST", l.Project.url()+"/links", b)
+	status, content, err := l.Project.Server.HTTPRequest("POST", l.Project.url("links"), b)
+	if err != nil {
+		return err
+	}
 	if !(status >= 200 && status < 300) {
-		serverError := ServerError{}
+		serverError := ServerError{Status: status}
 		json.Unmarshal(content, &serverError)
 		return &serverError
 	}
 	json.Unmarshal(content, l)
-	return err
+	return nil
 }
 
 // Delete deletes a link in the project
 func (l *Link) Delete() error {
 	status, content, err := l.Project.Server.HTTPRequest("DELETE", l.url(), nil)
+	if err != nil {
+		return err
+	}
 	if !(status >= 200 && status < 300) {
-		serverError := ServerError{}
+		serverError := ServerError{Status: status}
 		json.Unmarshal(content, &serverError)
 		return &serverError
 	}
-	return err
+	return nil
 }
 
 // Update updates a link in the project
 func (l *Link) Update() error {
-	var UUID = l.UUID
-	l.UUID = ""
-	b, _ := json.Marshal(l)
+	if l.Filters != nil {
+		if err := l.Project.Server.require(FeatureLinkFilters); err != nil {
+			return err
+		}
+	}
+	// the UUID is not sent: a copy is sent so that the link is not modified
+	// meanwhile
+	link := *l
+	link.UUID = ""
+	b, _ := json.Marshal(link)
+
+	status, content, err := l.Project.Server.HTTPRequest("PUT", l.url(), b)
+	if err != nil {
+		return err
+	}
+	if !(status >= 200 && status < 300) {
+		serverError := ServerError{Status: status}
+		json.Unmarshal(content, &serverError)
+		return &serverError
+	}
+	json.Unmarshal(content, l)
+	return nil
+}
+
+// StartCapture starts a packet capture on the link. The data link type is
+// one of the DLT_* values supported by the link (e.g. DLT_EN10MB for Ethernet)
+// and the file name is the name of the pcap file stored in the project.
+func (l *Link) StartCapture(dataLinkType string, fileName string) error {
+	capture := struct {
+		CaptureFileName string `json:"capture_file_name,omitempty"`
+		DataLinkType    string `json:"data_link_type,omitempty"`
+	}{
+		CaptureFileName: fileName,
+		DataLinkType:    dataLinkType,
+	}
+	b, _ := json.Marshal(capture)
+
+	status, content, err := l.Project.Server.HTTPRequest("POST", l.url(l.Project.Server.endpoint("start_capture")), b)
+	if err != nil {
+		return err
+	}
+	if !(status >= 200 && status < 300) {
+		serverError := ServerError{Status: status}
+		json.Unmarshal(content, &serverError)
+		return &serverError
+	}
+	json.Unmarshal(content, l)
+	return nil
+}
 
-	status, content, err := l.Project.Server.HTTPRequest("PUT", l.url()+UUID, b)
+// StopCapture stops the packet capture running on the link
+func (l *Link) StopCapture() error {
+	status, content, err := l.Project.Server.HTTPRequest("POST", l.url(l.Project.Server.endpoint("stop_capture")), nil)
+	if err != nil {
+		return err
+	}
 	if !(status >= 200 && status < 300) {
-		serverError := ServerError{}
+		serverError := ServerError{Status: status}
 		json.Unmarshal(content, &serverError)
 		return &serverError
 	}
 	json.Unmarshal(content, l)
+	return nil
+}
+
+// CaptureStream streams the pcap data of the capture running on the link. The
+// stream stays open as long as the capture runs, until the context is done or
+// the returned reader is closed.
+func (l *Link) CaptureStream(ctx context.Context) (io.ReadCloser, error) {
+	if err := l.Project.Server.require(FeatureCaptureStream); err != nil {
+		return nil, err
+	}
+	return l.Project.Server.HTTPStream(ctx, "GET", l.url(l.Project.Server.endpoint("pcap")), nil)
+}
+
+// AvailableFilters gets the list of the filters supported by the link
+func (l *Link) AvailableFilters() ([]LinkFilter, error) {
+	if err := l.Project.Server.require(FeatureLinkFilters); err != nil {
+		return nil, err
+	}
+	status, content, err := l.Project.Server.HTTPRequest("GET", l.url("available_filters"), nil)
+	if err != nil {
+		return nil, err
+	}
+	if !(status >= 200 && status < 300) {
+		serverError := ServerError{Status: status}
+		json.Unmarshal(content, &serverError)
+		return nil, &serverError
+	}
+
+	filters := []LinkFilter{}
+	json.Unmarshal(content, &filters)
+	return filters, nil
+}
+
+// LinkFlapPattern describes how a link is flapped: it is suspended for Down,
+// then resumed for Up, Count times. The link is left up after the last flap,
+// without waiting for Up.
+type LinkFlapPattern struct {
+	Count int
+	Down  time.Duration
+	Up    time.Duration
+}
 
-	return err
+func (l *Link) setSuspend(suspend bool) error {
+	if err := l.Project.Server.require(FeatureLinkSuspend); err != nil {
+		return err
+	}
+	b, _ := json.Marshal(struct {
+		Suspend bool `json:"suspend"`
+	}{suspend})
+
+	status, content, err := l.Project.Server.HTTPRequest("PUT", l.url(), b)
+	if err != nil {
+		return err
+	}
+	if !(status >= 200 && status < 300) {
+		serverError := ServerError{Status: status}
+		json.Unmarshal(content, &serverError)
+		return &serverError
+	}
+	json.Unmarshal(content, l)
+	return nil
+}
+
+// SuspendLink suspends the link, i.e. the packets are not forwarded anymore
+func (l *Link) SuspendLink() error {
+	return l.setSuspend(true)
+}
+
+// ResumeLink resumes a suspended link
+func (l *Link) ResumeLink() error {
+	return l.setSuspend(false)
+}
+
+// Flap suspends and resumes the link following the given pattern. If the
+// context is done while the link is suspended, the link is resumed before
+// returning the context error.
+func (l *Link) Flap(ctx context.Context, pattern LinkFlapPattern) error {
+	for i := 0; i < pattern.Count; i++ {
+		if err := l.SuspendLink(); err != nil {
+			return err
+		}
+		if err := sleepContext(ctx, pattern.Down); err != nil {
+			l.ResumeLink()
+			return err
+		}
+		if err := l.ResumeLink(); err != nil {
+			return err
+		}
+		if i == pattern.Count-1 {
+			break
+		}
+		if err := sleepContext(ctx, pattern.Up); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// sleepContext waits for the given duration, unless the context is done first
+func sleepContext(ctx context.Context, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
 }
@@ -1,7 +1,11 @@
 package gogns3
 
 import (
+	"context"
+	"encoding/json"
+	"errors"
 	"testing"
+	"time"
 )
 
 func resetTestLinkEthernetSwitchLab(t *testing.T) (*Project, *Node, *Node) {
@@ -320,3 +324,213 @@ func TestNodeVpcsLinkCreate(t *testing.T) {
 
 	createAndTestLink(t, p, n1, n2)
 }
+
+func TestNodeEthernetSwitchLinkCapture(t *testing.T) {
+	_, _, _, l := resetTestLinkEthernetSwitchLab2(t)
+
+	if err := l.StartCapture("DLT_EN10MB", "gogns3.pcap"); err != nil {
+		t.Error("Could not start a capture on an existing link")
+		t.Error(err)
+	}
+	if l.Capturing != true {
+		t.Error("This link seems to be misconfigured (capturing != true)")
+	}
+	if l.CaptureFileName != "gogns3.pcap" {
+		t.Error("This link seems to be misconfigured (capture_file_name != gogns3.pcap)")
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+	r, err := l.CaptureStream(ctx)
+	if err != nil {
+		t.Error("Could not stream the capture of an existing link")
+		t.Error(err)
+	} else {
+		r.Close()
+	}
+
+	if err := l.StopCapture(); err != nil {
+		t.Error("Could not stop a capture on an existing link")
+		t.Error(err)
+	}
+	if l.Capturing != false {
+		t.Error("This link seems to be misconfigured (capturing != false)")
+	}
+}
+
+func TestNodeEthernetSwitchLinkCaptureError(t *testing.T) {
+	_, _, _, l := resetTestLinkEthernetSwitchLab2(t)
+	l.UUID = "11111111-1111-1111-1111-111111111111"
+
+	if err := l.StartCapture("DLT_EN10MB", "gogns3.pcap"); err != nil {
+		switch e := err.(type) {
+		case *ServerError:
+			if e.Status != 404 {
+				t.Error(e)
+			}
+		default:
+			t.Error(e)
+		}
+	}
+}
+
+func TestLinkFiltersMarshal(t *testing.T) {
+	loss := 10
+	f := LinkFilters{
+		PacketLoss: &loss,
+		Delay:      &LinkDelay{Latency: 100, Jitter: 20},
+		BPF:        "icmp",
+	}
+
+	b, err := json.Marshal(f)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(b) != `{"bpf":["icmp"],"delay":[100,20],"packet_loss":[10]}` {
+		t.Errorf("Filters seem to be misencoded (%s)", b)
+	}
+
+	f = LinkFilters{}
+	if err := json.Unmarshal(b, &f); err != nil {
+		t.Fatal(err)
+	}
+	if f.PacketLoss == nil || *f.PacketLoss != 10 {
+		t.Error("Filters seem to be misdecoded (packet_loss != 10)")
+	}
+	if f.Delay == nil || f.Delay.Latency != 100 || f.Delay.Jitter != 20 {
+		t.Error("Filters seem to be misdecoded (delay != 100/20)")
+	}
+	if f.BPF != "icmp" {
+		t.Error("Filters seem to be misdecoded (bpf != icmp)")
+	}
+	if f.FrequencyDrop != nil || f.Corrupt != nil {
+		t.Error("Filters seem to be misdecoded (unexpected filters)")
+	}
+}
+
+func TestNodeEthernetSwitchLinkFiltersUpdate(t *testing.T) {
+	_, _, _, l := resetTestLinkEthernetSwitchLab2(t)
+
+	loss := 25
+	l.Filters = &LinkFilters{
+		PacketLoss: &loss,
+		Delay:      &LinkDelay{Latency: 50, Jitter: 10},
+	}
+	err := l.Update()
+
+	l.Read()
+	if err != nil {
+		t.Error("Could not update the filters of an existing link")
+		t.Error(err)
+	}
+	if l.Filters == nil || l.Filters.PacketLoss == nil || *l.Filters.PacketLoss != 25 {
+		t.Error("This link seems to be misconfigured (packet_loss != 25)")
+	}
+	if l.Filters == nil || l.Filters.Delay == nil || l.Filters.Delay.Latency != 50 {
+		t.Error("This link seems to be misconfigured (delay != 50)")
+	}
+}
+
+func TestNodeEthernetSwitchLinkAvailableFilters(t *testing.T) {
+	_, _, _, l := resetTestLinkEthernetSwitchLab2(t)
+
+	filters, err := l.AvailableFilters()
+	if err != nil {
+		t.Error(err)
+	}
+	found := false
+	for _, f := range filters {
+		if f.Type == "packet_loss" {
+			found = true
+		}
+	}
+	if !found {
+		t.Error("The packet_loss filter must be available on this link")
+	}
+}
+
+func TestNodeEthernetSwitchLinkSuspendResume(t *testing.T) {
+	_, _, _, l := resetTestLinkEthernetSwitchLab2(t)
+
+	if err := l.SuspendLink(); err != nil {
+		t.Error("Could not suspend an existing link")
+		t.Error(err)
+	}
+	l.Read()
+	if l.Suspend != true {
+		t.Error("This link seems to be misconfigured (suspend != true)")
+	}
+
+	if err := l.ResumeLink(); err != nil {
+		t.Error("Could not resume an existing link")
+		t.Error(err)
+	}
+	l.Read()
+	if l.Suspend != false {
+		t.Error("This link seems to be misconfigured (suspend != false)")
+	}
+}
+
+func TestNodeEthernetSwitchLinkFlap(t *testing.T) {
+	_, _, _, l := resetTestLinkEthernetSwitchLab2(t)
+
+	pattern := LinkFlapPattern{Count: 2, Down: 100 * time.Millisecond, Up: 100 * time.Millisecond}
+	if err := l.Flap(context.Background(), pattern); err != nil {
+		t.Error("Could not flap an existing link")
+		t.Error(err)
+	}
+	l.Read()
+	if l.Suspend != false {
+		t.Error("This link must be resumed after flapping (suspend != false)")
+	}
+}
+
+func TestNodeEthernetSwitchLinkFlapCancel(t *testing.T) {
+	_, _, _, l := resetTestLinkEthernetSwitchLab2(t)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	pattern := LinkFlapPattern{Count: 1, Down: time.Minute}
+	if err := l.Flap(ctx, pattern); err != context.DeadlineExceeded {
+		t.Error("Flapping must stop when the context is done")
+	}
+	l.Read()
+	if l.Suspend != false {
+		t.Error("This link must be resumed after flapping (suspend != false)")
+	}
+}
+
+func TestLinkCaptureConnectionError(t *testing.T) {
+	l := Link{Project: &Project{Server: newTestUnreachableServer(), UUID: "1"}, UUID: "2"}
+
+	serverError := &ServerError{}
+	if err := l.StartCapture("DLT_EN10MB", ""); err == nil || errors.As(err, &serverError) {
+		t.Errorf("A connection error must be returned as is (%v)", err)
+	}
+	if err := l.StopCapture(); err == nil || errors.As(err, &serverError) {
+		t.Errorf("A connection error must be returned as is (%v)", err)
+	}
+}
+
+func TestLinkAvailableFiltersConnectionError(t *testing.T) {
+	s := newTestUnreachableServer()
+	// the version is cached so that the feature check passes
+	s.version = &Version{Version: "2.2.17"}
+	l := Link{Project: &Project{Server: s, UUID: "1"}, UUID: "2"}
+
+	serverError := &ServerError{}
+	if _, err := l.AvailableFilters(); err == nil || errors.As(err, &serverError) {
+		t.Errorf("A connection error must be returned as is (%v)", err)
+	}
+}
+
+func TestLinkSuspendConnectionError(t *testing.T) {
+	s := newTestUnreachableServer()
+	s.version = &Version{Version: "2.2.17"}
+	l := Link{Project: &Project{Server: s, UUID: "1"}, UUID: "2"}
+
+	serverError := &ServerError{}
+	if err := l.SuspendLink(); err == nil || errors.As(err, &serverError) {
+		t.Errorf("A connection error must be returned as is (%v)", err)
+	}
+}
@@ -1,6 +1,11 @@
 package gogns3
 
-import "encoding/json"
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+)
 
 // Label of a node or link
 type Label struct {
@@ -18,8 +23,11 @@ type Label struct {
 // marshalling problem with some custom MarshalJSON() functions, but nothing
 // special to do when unmarshalling. Note than the omitempty keyword is useless
 // with bool type as missing value does not mean false for the GNS3 server API.
+// The other node types have no such structure: their properties are kept as
+// read and written out unchanged.
 type NodeProperties struct {
 	nodeType           string
+	raw                map[string]json.RawMessage
 	AdapterType        string                     `json:"adapter_type"`
 	Adapters           int                        `json:"adapters"`
 	BiosImage          string                     `json:"bios_image"`
@@ -29,6 +37,7 @@ type NodeProperties struct {
 	CdromImageMd5sum   string                     `json:"cdrom_image_md5sum"`
 	CPUThrottling      int                        `json:"cpu_throttling"`
 	CPUs               int                        `json:"cpus"`
+	DynamipsID         int                        `json:"dynamips_id"`
 	HdaDiskImage       string                     `json:"hda_disk_image"`
 	HdaDiskImageMd5sum string                     `json:"hda_disk_image_md5sum"`
 	HdaDiskInterface   string                     `json:"hda_disk_interface"`
@@ -59,6 +68,7 @@ type NodeProperties struct {
 
 type nodeEthernetSwitchProperties struct {
 	nodeType           string
+	raw                map[string]json.RawMessage
 	AdapterType        string                     `json:"-"`
 	Adapters           int                        `json:"-"`
 	BiosImage          string                     `json:"-"`
@@ -68,6 +78,7 @@ type nodeEthernetSwitchProperties struct {
 	CdromImageMd5sum   string                     `json:"-"`
 	CPUThrottling      int                        `json:"-"`
 	CPUs               int                        `json:"-"`
+	DynamipsID         int                        `json:"-"`
 	HdaDiskImage       string                     `json:"-"`
 	HdaDiskImageMd5sum string                     `json:"-"`
 	HdaDiskInterface   string                     `json:"-"`
@@ -98,6 +109,7 @@ type nodeEthernetSwitchProperties struct {
 
 type nodeQemuProperties struct {
 	nodeType           string
+	raw                map[string]json.RawMessage
 	AdapterType        string                     `json:"adapter_type,omitempty"`
 	Adapters           int                        `json:"adapters,omitempty"`
 	BiosImage          string                     `json:"bios_image,omitempty"`
@@ -107,6 +119,7 @@ type nodeQemuProperties struct {
 	CdromImageMd5sum   string                     `json:"cdrom_image_md5sum,omitempty"`
 	CPUThrottling      int                        `json:"cpu_throttling,omitempty"`
 	CPUs               int                        `json:"cpus,omitempty"`
+	DynamipsID         int                        `json:"-"`
 	HdaDiskImage       string                     `json:"hda_disk_image,omitempty"`
 	HdaDiskImageMd5sum string                     `json:"hda_disk_image_md5sum,omitempty"`
 	HdaDiskInterface   string                     `json:"hda_disk_interface,omitempty"`
@@ -137,6 +150,7 @@ type nodeQemuProperties struct {
 
 type nodeVpcsProperties struct {
 	nodeType           string
+	raw                map[string]json.RawMessage
 	AdapterType        string                     `json:"-"`
 	Adapters           int                        `json:"-"`
 	BiosImage          string                     `json:"-"`
@@ -146,6 +160,7 @@ type nodeVpcsProperties struct {
 	CdromImageMd5sum   string                     `json:"-"`
 	CPUThrottling      int                        `json:"-"`
 	CPUs               int                        `json:"-"`
+	DynamipsID         int                        `json:"-"`
 	HdaDiskImage       string                     `json:"-"`
 	HdaDiskImageMd5sum string                     `json:"-"`
 	HdaDiskInterface   string                     `json:"-"`
@@ -187,7 +202,32 @@ func (p NodeProperties) MarshalJSON() ([]byte, error) {
 	case "vpcs":
 		return json.Marshal(nodeVpcsProperties(p))
 	}
-	return json.Marshal(nil)
+	if p.raw == nil {
+		return json.Marshal(nil)
+	}
+	return json.Marshal(p.raw)
+}
+
+type nodePropertiesAlias NodeProperties
+
+// UnmarshalJSON reads the fields of the properties and keeps them as read for
+// the node types without a properties structure. Like the fields, the raw
+// properties read are merged with the previous ones.
+func (p *NodeProperties) UnmarshalJSON(b []byte) error {
+	alias := nodePropertiesAlias(*p)
+	if err := json.Unmarshal(b, &alias); err != nil {
+		return err
+	}
+	raw := map[string]json.RawMessage{}
+	for key, value := range p.raw {
+		raw[key] = value
+	}
+	if err := json.Unmarshal(b, &raw); err != nil {
+		return err
+	}
+	*p = NodeProperties(alias)
+	p.raw = raw
+	return nil
 }
 
 // NodeEthernetPortsMapping are specific to an Ethernet switch
@@ -204,6 +244,7 @@ type Node struct {
 	CommandLine     string         `json:"command_line,omitempty"`
 	ComputeID       string         `json:"compute_id"`
 	Console         int            `json:"console,omitempty"`
+	ConsoleHost     string         `json:"console_host,omitempty"`
 	ConsoleType     string         `json:"console_type,omitempty"`
 	FirstPortName   string         `json:"first_port_name,omitempty"`
 	Label           *Label         `json:"label,omitempty"`
@@ -211,6 +252,7 @@ type Node struct {
 	NodeType        string         `json:"node_type"`
 	PortNameFormat  string         `json:"port_name_format,omitempty"`
 	PortSegmentSize int            `json:"port_segment_size,omitempty"`
+	Ports           []NodePort     `json:"ports,omitempty"`
 	Project         *Project       `json:"-"`
 	Properties      NodeProperties `json:"properties,omitempty"`
 	Status          string         `json:"status,omitempty"`
@@ -228,27 +270,55 @@ type nodeAlias Node
 // object type for casting. The nodeAlias prevents infinite loop recursions.
 func (n Node) MarshalJSON() ([]byte, error) {
 	n.Properties.nodeType = n.NodeType
+	// the console host and the ports are set by the server and cannot be
+	// changed
+	n.ConsoleHost = ""
+	n.Ports = nil
 	return json.Marshal(nodeAlias(n))
 }
 
-func (n *Node) url() string {
-	return n.Project.url() + "/nodes/" + n.UUID
+// url returns the URL of the node, or of the given path under it
+func (n *Node) url(elements ...string) string {
+	return n.Project.url(append([]string{"nodes", n.UUID}, elements...)...)
 }
 
-// Read reads an existing node in the project
+// Read reads an existing node in the project, by UUID if it is known, by name
+// otherwise. An AmbiguousError is returned if several nodes have the name.
 func (n *Node) Read() error {
-	// save the project information as it will be reset
-	project := n.Project
-	nodes, _ := n.Project.GetNodes()
-	for _, node := range nodes {
-		if node.Name == n.Name {
-			*n = node
-			// restore the project information
-			n.Project = project
-			return nil
+	if n.UUID != "" {
+		status, content, err := n.Project.Server.HTTPRequest("GET", n.url(), nil)
+		if err != nil {
+			return err
 		}
+		if !(status >= 200 && status < 300) {
+			serverError := ServerError{Status: status}
+			json.Unmarshal(content, &serverError)
+			return &serverError
+		}
+		node := Node{Project: n.Project}
+		if err := json.Unmarshal(content, &node); err != nil {
+			return err
+		}
+		*n = node
+		return nil
+	}
+
+	nodes, err := n.Project.FindNodes(NodeName(Exact(n.Name)))
+	if err != nil {
+		return err
 	}
-	return &ServerError{Status: 404, Message: "Node does not exist in the project"}
+	switch len(nodes) {
+	case 0:
+		return &ServerError{Status: 404, Message: "Node does not exist in the project"}
+	case 1:
+		*n = nodes[0]
+		return nil
+	}
+	ambiguousError := &AmbiguousError{Kind: "node", Name: n.Name}
+	for _, node := range nodes {
+		ambiguousError.UUIDs = append(ambiguousError.UUIDs, node.UUID)
+	}
+	return ambiguousError
 }
 
 // Exists checks a node exists in the project
@@ -257,53 +327,203 @@ func (n *Node) Exists() (bool, error) {
 	node := Node{
 		Name:    n.Name,
 		Project: n.Project,
+		UUID:    n.UUID,
 	}
 
 	err := node.Read()
 	return err == nil, err
 }
 
-// Create creates a node in the project
+// Create creates a node in the project. The default compute of the server is
+// used when the node has none.
 func (n *Node) Create() error {
-	b, _ := json.Marshal(n)
-	status, content, err := n.Project.Server.HTTPRequest("POST", n.Project.url()+"/nodes", b)
+	node := *n
+	if node.ComputeID == "" {
+		node.ComputeID = n.Project.Server.ComputeID
+	}
+	b, _ := json.Marshal(node)
+	status, content, err := n.Project.Server.HTTPRequest("POST", n.Project.url("nodes"), b)
+	if err != nil {
+		return err
+	}
 	if !(status >= 200 && status < 300) {
-		serverError := ServerError{}
+		serverError := ServerError{Status: status}
 		json.Unmarshal(content, &serverError)
 		return &serverError
 	}
 	json.Unmarshal(content, n)
-	return err
+	return nil
 }
 
 // Delete deletes a node in the project
 // Read() may be called before a Delete() can be executed
 func (n *Node) Delete() error {
 	if n.UUID == "" {
-		n.Read()
+		if err := n.Read(); err != nil {
+			return err
+		}
 	}
 	status, content, err := n.Project.Server.HTTPRequest("DELETE", n.url(), nil)
+	if err != nil {
+		return err
+	}
 	if !(status >= 200 && status < 300) {
-		serverError := ServerError{}
+		serverError := ServerError{Status: status}
 		json.Unmarshal(content, &serverError)
 		return &serverError
 	}
-	return err
+	return nil
 }
 
 // Update updates a node in the project
 func (n *Node) Update() error {
-	var UUID = n.UUID
-	n.UUID = ""
-	b, _ := json.Marshal(n)
+	// the UUID is not sent: a copy is sent so that the node is not modified
+	// meanwhile
+	node := *n
+	node.UUID = ""
+	b, _ := json.Marshal(node)
 
-	status, content, err := n.Project.Server.HTTPRequest("PUT", n.url()+UUID, b)
+	status, content, err := n.Project.Server.HTTPRequest("PUT", n.url(), b)
+	if err != nil {
+		return err
+	}
 	if !(status >= 200 && status < 300) {
-		serverError := ServerError{}
+		serverError := ServerError{Status: status}
 		json.Unmarshal(content, &serverError)
 		return &serverError
 	}
 	json.Unmarshal(content, n)
+	return nil
+}
+
+// Start starts a node
+func (n *Node) Start() error {
+	return n.action("start")
+}
+
+// Stop stops a node
+func (n *Node) Stop() error {
+	return n.action("stop")
+}
+
+// Reload reloads a node, i.e. stops and starts it again
+func (n *Node) Reload() error {
+	return n.action("reload")
+}
 
-	return err
+// action executes an action on a node and updates it with the answer of the
+// server
+// Read() may be called before an action can be executed
+func (n *Node) action(action string) error {
+	if n.UUID == "" {
+		if err := n.Read(); err != nil {
+			return err
+		}
+	}
+	status, content, err := n.Project.Server.HTTPRequest("POST", n.url(action), nil)
+	if err != nil {
+		return err
+	}
+	if !(status >= 200 && status < 300) {
+		serverError := ServerError{Status: status}
+		json.Unmarshal(content, &serverError)
+		return &serverError
+	}
+	json.Unmarshal(content, n)
+	return nil
+}
+
+// ErrConfigUnsupported is returned when a configuration file is requested for
+// a node type which does not have one
+var ErrConfigUnsupported = errors.New("configuration file not supported by this node type")
+
+// ReadFile reads a file of the node working directory. The path is relative to
+// this directory. The caller must close the returned reader. The transfer is
+// cancelled when no data is received within the timeout of the server.
+func (n *Node) ReadFile(path string) (io.ReadCloser, error) {
+	return n.Project.Server.fileStream("GET", n.url("files", path), nil)
+}
+
+// WriteFile writes a file in the node working directory. The path is relative
+// to this directory. The transfer is cancelled when no data is sent or received
+// within the timeout of the server.
+func (n *Node) WriteFile(path string, r io.Reader) error {
+	body, err := n.Project.Server.fileStream("POST", n.url("files", path), r)
+	if err != nil {
+		return err
+	}
+	return body.Close()
+}
+
+// startupConfigPath returns the path of the startup configuration, which
+// depends on the node type
+func (n *Node) startupConfigPath() (string, error) {
+	switch n.NodeType {
+	case "vpcs":
+		return "startup.vpc", nil
+	case "iou":
+		return "startup-config.cfg", nil
+	case "dynamips":
+		if n.Properties.DynamipsID == 0 {
+			return "", ErrConfigUnsupported
+		}
+		return fmt.Sprintf("configs/i%d_startup-config.cfg", n.Properties.DynamipsID), nil
+	}
+	return "", ErrConfigUnsupported
+}
+
+// privateConfigPath returns the path of the private configuration, which
+// depends on the node type
+func (n *Node) privateConfigPath() (string, error) {
+	switch n.NodeType {
+	case "iou":
+		return "private-config.cfg", nil
+	case "dynamips":
+		if n.Properties.DynamipsID == 0 {
+			return "", ErrConfigUnsupported
+		}
+		return fmt.Sprintf("configs/i%d_private-config.cfg", n.Properties.DynamipsID), nil
+	}
+	return "", ErrConfigUnsupported
+}
+
+// ReadStartupConfig reads the startup configuration of the node, i.e. the
+// startup-config of a Dynamips or IOU router, or the startup.vpc of a VPCS
+// node. Dynamips nodes must have been read from the server first.
+func (n *Node) ReadStartupConfig() (io.ReadCloser, error) {
+	path, err := n.startupConfigPath()
+	if err != nil {
+		return nil, err
+	}
+	return n.ReadFile(path)
+}
+
+// WriteStartupConfig writes the startup configuration of the node. It must be
+// written before the node is started to be taken into account.
+func (n *Node) WriteStartupConfig(r io.Reader) error {
+	path, err := n.startupConfigPath()
+	if err != nil {
+		return err
+	}
+	return n.WriteFile(path, r)
+}
+
+// ReadPrivateConfig reads the private configuration of a Dynamips or IOU
+// router
+func (n *Node) ReadPrivateConfig() (io.ReadCloser, error) {
+	path, err := n.privateConfigPath()
+	if err != nil {
+		return nil, err
+	}
+	return n.ReadFile(path)
+}
+
+// WritePrivateConfig writes the private configuration of a Dynamips or IOU
+// router
+func (n *Node) WritePrivateConfig(r io.Reader) error {
+	path, err := n.privateConfigPath()
+	if err != nil {
+		return err
+	}
+	return n.WriteFile(path, r)
 }
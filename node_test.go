@@ -1,7 +1,14 @@
 package gogns3
 
 import (
+	"context"
+	"errors"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"strings"
 	"testing"
+	"time"
 )
 
 func resetTestNodeEthernetSwitch(t *testing.T) *Node {
@@ -592,3 +599,157 @@ func TestNodeVpcsDelete(t *testing.T) {
 		t.Error(err)
 	}
 }
+
+func TestNodeVpcsStartStop(t *testing.T) {
+	n := resetTestNodeVpcs(t)
+
+	if err := n.Start(); err != nil {
+		t.Error("Could not start an existing node")
+		t.Error(err)
+	}
+	if n.Status != "started" {
+		t.Error("This node seems to be stopped (status != started)")
+	}
+
+	if err := n.Stop(); err != nil {
+		t.Error("Could not stop an existing node")
+		t.Error(err)
+	}
+	if n.Status != "stopped" {
+		t.Error("This node seems to be started (status != stopped)")
+	}
+}
+
+func TestNodeVpcsStartupConfig(t *testing.T) {
+	n := resetTestNodeVpcs(t)
+
+	if err := n.WriteStartupConfig(strings.NewReader("ip 10.0.0.1/24\n")); err != nil {
+		t.Error("Could not write the startup configuration of an existing node")
+		t.Error(err)
+	}
+
+	r, err := n.ReadFile("startup.vpc")
+	if err != nil {
+		t.Error("Could not read the startup configuration of an existing node")
+		t.Error(err)
+		return
+	}
+	defer r.Close()
+	b, _ := ioutil.ReadAll(r)
+	if string(b) != "ip 10.0.0.1/24\n" {
+		t.Error("This node seems to be misconfigured (startup.vpc != ip 10.0.0.1/24)")
+	}
+}
+
+func TestNodeVpcsReadFileError(t *testing.T) {
+	n := resetTestNodeVpcs(t)
+
+	if _, err := n.ReadFile("fakefakefake.txt"); err != nil {
+		switch e := err.(type) {
+		case *ServerError:
+			if e.Status != 404 {
+				t.Error(e)
+			}
+		default:
+			t.Error(e)
+		}
+	}
+}
+
+func TestNodeStartupConfigError(t *testing.T) {
+	n := Node{
+		Name:     "SW1",
+		NodeType: "ethernet_switch",
+	}
+
+	if _, err := n.ReadStartupConfig(); err != ErrConfigUnsupported {
+		t.Error("An Ethernet switch must not have a startup configuration")
+	}
+	if err := n.WritePrivateConfig(strings.NewReader("")); err != ErrConfigUnsupported {
+		t.Error("An Ethernet switch must not have a private configuration")
+	}
+}
+
+func TestNodeReadByUUID(t *testing.T) {
+	s, stop := newTestNodesServer()
+	defer stop()
+	s.APIVersion = APIv2
+	p := &Project{Server: s, UUID: "1"}
+	created := []Node{{Name: "PC1", NodeType: "vpcs"}, {Name: "PC1", NodeType: "vpcs"}}
+	if _, err := p.CreateNodes(created); err != nil {
+		t.Fatal(err)
+	}
+
+	// the second node has the same name, it can only be read by UUID
+	n := Node{Project: p, UUID: created[1].UUID}
+	if err := n.Read(); err != nil || n.Name != "PC1" || n.UUID != created[1].UUID || n.Project != p {
+		t.Errorf("This node seems to be misread (%+v, %v)", n, err)
+	}
+
+	n = Node{Project: p, UUID: "unknown"}
+	serverError := &ServerError{}
+	if err := n.Read(); !errors.As(err, &serverError) || serverError.Status != 404 {
+		t.Errorf("Reading an unknown node must fail with a 404 error (%v)", err)
+	}
+}
+
+func TestNodeActionConnectionError(t *testing.T) {
+	p := &Project{Server: newTestUnreachableServer(), UUID: "1"}
+	n := Node{Project: p, UUID: "2"}
+
+	serverError := &ServerError{}
+	for _, err := range []error{n.Start(), n.Stop(), n.Reload(), p.Open(), p.Close()} {
+		if err == nil || errors.As(err, &serverError) {
+			t.Errorf("A connection error must be returned as is (%v)", err)
+		}
+	}
+}
+
+func TestNodeFileTimeout(t *testing.T) {
+	s, stop := newTestHTTPServer(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/v2/projects/1/nodes/2/files/slow.txt":
+			// no answer within the timeout
+			time.Sleep(200 * time.Millisecond)
+		case "/v2/projects/1/nodes/2/files/large.txt":
+			// the file is sent slowly, but the data keeps flowing
+			for i := 0; i < 4; i++ {
+				w.Write([]byte("data\n"))
+				w.(http.Flusher).Flush()
+				time.Sleep(30 * time.Millisecond)
+			}
+		case "/v2/projects/1/nodes/2/files/upload.txt":
+			ioutil.ReadAll(r.Body)
+		}
+	})
+	defer stop()
+	s.APIVersion, s.Timeout = APIv2, 50*time.Millisecond
+	n := &Node{Project: &Project{Server: s, UUID: "1"}, UUID: "2"}
+
+	if _, err := n.ReadFile("slow.txt"); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Reading a file must time out when the server does not answer (%v)", err)
+	}
+
+	r, err := n.ReadFile("large.txt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, err := ioutil.ReadAll(r)
+	r.Close()
+	if err != nil || len(b) != 20 {
+		t.Errorf("A file received for longer than the timeout must be read (%d bytes, %v)", len(b), err)
+	}
+
+	// the file is written slowly, but the data keeps flowing
+	pr, pw := io.Pipe()
+	go func() {
+		for i := 0; i < 4; i++ {
+			pw.Write([]byte("data\n"))
+			time.Sleep(30 * time.Millisecond)
+		}
+		pw.Close()
+	}()
+	if err := n.WriteFile("upload.txt", pr); err != nil {
+		t.Errorf("A file sent for longer than the timeout must be written (%v)", err)
+	}
+}
@@ -0,0 +1,504 @@
+package gogns3
+
+import (
+	"bufio"
+	"context"
+	"encoding/binary"
+	"errors"
+	"io"
+	"math"
+	"net"
+	"time"
+)
+
+// Link types of the captured packets, as defined by tcpdump.org
+const (
+	LinkTypeEthernet = 1
+)
+
+// EtherType values decoded by the pcap reader
+const (
+	EtherTypeIPv4 = 0x0800
+	EtherTypeARP  = 0x0806
+	EtherTypeVLAN = 0x8100
+	EtherTypeIPv6 = 0x86dd
+)
+
+// IP protocol numbers commonly found in lab captures
+const (
+	IPProtocolICMP   = 1
+	IPProtocolTCP    = 6
+	IPProtocolUDP    = 17
+	IPProtocolICMPv6 = 58
+	IPProtocolOSPF   = 89
+)
+
+const (
+	pcapMagicMicroseconds = 0xa1b2c3d4
+	pcapMagicNanoseconds  = 0xa1b23c4d
+	pcapngSectionHeader   = 0x0a0d0d0a
+	pcapngByteOrderMagic  = 0x1a2b3c4d
+	pcapngInterface       = 0x00000001
+	pcapngSimplePacket    = 0x00000003
+	pcapngEnhancedPacket  = 0x00000006
+	pcapngMaxBlockLength  = 16 * 1024 * 1024
+	pcapMaxRecordLength   = 256 * 1024
+)
+
+// ErrPcapFormat is returned when the stream is neither a pcap nor a pcapng stream
+var ErrPcapFormat = errors.New("invalid pcap format")
+
+// EthernetHeader is the decoded header of an Ethernet frame. VLAN is set when
+// the frame carries an 802.1Q tag, EtherType is then the inner EtherType.
+type EthernetHeader struct {
+	Destination net.HardwareAddr
+	Source      net.HardwareAddr
+	EtherType   uint16
+	VLAN        uint16
+}
+
+// ARPHeader is the decoded header of an ARP packet
+type ARPHeader struct {
+	HardwareType          uint16
+	ProtocolType          uint16
+	Operation             uint16
+	SenderHardwareAddress net.HardwareAddr
+	SenderProtocolAddress net.IP
+	TargetHardwareAddress net.HardwareAddr
+	TargetProtocolAddress net.IP
+}
+
+// IPv4Header is the decoded header of an IPv4 packet
+type IPv4Header struct {
+	Version        uint8
+	IHL            uint8
+	TOS            uint8
+	TotalLength    uint16
+	ID             uint16
+	Flags          uint8
+	FragmentOffset uint16
+	TTL            uint8
+	Protocol       uint8
+	Checksum       uint16
+	Source         net.IP
+	Destination    net.IP
+	Options        []byte
+}
+
+// IPv6Header is the decoded fixed header of an IPv6 packet
+type IPv6Header struct {
+	Version       uint8
+	TrafficClass  uint8
+	FlowLabel     uint32
+	PayloadLength uint16
+	NextHeader    uint8
+	HopLimit      uint8
+	Source        net.IP
+	Destination   net.IP
+}
+
+// ICMPHeader is the decoded header of an ICMP or ICMPv6 message
+type ICMPHeader struct {
+	Type     uint8
+	Code     uint8
+	Checksum uint16
+}
+
+// Packet is a captured packet. Headers which could not be found or decoded are
+// left nil and Payload holds the bytes following the last decoded header.
+type Packet struct {
+	Timestamp time.Time
+	Length    int
+	LinkType  uint32
+	Data      []byte
+	Ethernet  *EthernetHeader
+	ARP       *ARPHeader
+	IPv4      *IPv4Header
+	IPv6      *IPv6Header
+	ICMP      *ICMPHeader
+	Payload   []byte
+}
+
+// PcapReader reads packets from a pcap or pcapng stream, such as the one
+// returned by Link.CaptureStream()
+type PcapReader struct {
+	r          *bufio.Reader
+	closer     io.Closer
+	byteOrder  binary.ByteOrder
+	ng         bool
+	linkType   uint32
+	nanosecond bool
+	snaplen    uint32
+	interfaces []pcapngInterfaceInfo
+}
+
+type pcapngInterfaceInfo struct {
+	linkType   uint32
+	resolution float64
+}
+
+// NewPcapReader reads the header of a pcap or pcapng stream and returns a
+// reader for its packets. If r is an io.Closer, it is closed by Close().
+func NewPcapReader(r io.Reader) (*PcapReader, error) {
+	p := &PcapReader{r: bufio.NewReader(r)}
+	if c, ok := r.(io.Closer); ok {
+		p.closer = c
+	}
+
+	magic, err := p.r.Peek(4)
+	if err != nil {
+		return nil, err
+	}
+	switch {
+	case binary.BigEndian.Uint32(magic) == pcapngSectionHeader:
+		p.ng = true
+		return p, p.readSectionHeader()
+	case binary.LittleEndian.Uint32(magic) == pcapMagicMicroseconds:
+		p.byteOrder = binary.LittleEndian
+	case binary.BigEndian.Uint32(magic) == pcapMagicMicroseconds:
+		p.byteOrder = binary.BigEndian
+	case binary.LittleEndian.Uint32(magic) == pcapMagicNanoseconds:
+		p.byteOrder, p.nanosecond = binary.LittleEndian, true
+	case binary.BigEndian.Uint32(magic) == pcapMagicNanoseconds:
+		p.byteOrder, p.nanosecond = binary.BigEndian, true
+	default:
+		return nil, ErrPcapFormat
+	}
+
+	header := make([]byte, 24)
+	if _, err := io.ReadFull(p.r, header); err != nil {
+		return nil, err
+	}
+	p.snaplen = p.byteOrder.Uint32(header[16:20])
+	p.linkType = p.byteOrder.Uint32(header[20:24]) & 0x0fffffff
+	return p, nil
+}
+
+// CaptureReader streams the capture running on the link and returns a reader
+// decoding its packets. The reader must be closed to end the stream.
+func (l *Link) CaptureReader(ctx context.Context) (*PcapReader, error) {
+	stream, err := l.CaptureStream(ctx)
+	if err != nil {
+		return nil, err
+	}
+	p, err := NewPcapReader(stream)
+	if err != nil {
+		stream.Close()
+		return nil, err
+	}
+	return p, nil
+}
+
+// Close closes the underlying stream, if it can be closed
+func (p *PcapReader) Close() error {
+	if p.closer == nil {
+		return nil
+	}
+	return p.closer.Close()
+}
+
+// Next reads and decodes the next packet. It returns io.EOF at the end of the
+// stream.
+func (p *PcapReader) Next() (*Packet, error) {
+	if p.ng {
+		return p.nextBlock()
+	}
+
+	header := make([]byte, 16)
+	if _, err := io.ReadFull(p.r, header); err != nil {
+		return nil, err
+	}
+	seconds := int64(p.byteOrder.Uint32(header[0:4]))
+	fraction := int64(p.byteOrder.Uint32(header[4:8]))
+	if !p.nanosecond {
+		fraction *= 1000
+	}
+	// the captured length is checked before allocating the packet data, a
+	// corrupt record must not allocate up to 4 GiB
+	length := p.byteOrder.Uint32(header[8:12])
+	if length > pcapMaxRecordLength || p.snaplen != 0 && length > p.snaplen {
+		return nil, ErrPcapFormat
+	}
+	data := make([]byte, length)
+	if _, err := io.ReadFull(p.r, data); err != nil {
+		return nil, err
+	}
+
+	packet := &Packet{
+		Timestamp: time.Unix(seconds, fraction),
+		Length:    int(p.byteOrder.Uint32(header[12:16])),
+		LinkType:  p.linkType,
+		Data:      data,
+	}
+	packet.decode()
+	return packet, nil
+}
+
+// readBlock reads a whole pcapng block and returns its type and body
+func (p *PcapReader) readBlock() (uint32, []byte, error) {
+	header := make([]byte, 8)
+	if _, err := io.ReadFull(p.r, header); err != nil {
+		return 0, nil, err
+	}
+	blockType := p.byteOrder.Uint32(header[0:4])
+	length := p.byteOrder.Uint32(header[4:8])
+	if length < 12 || length > pcapngMaxBlockLength || length%4 != 0 {
+		return 0, nil, ErrPcapFormat
+	}
+	block := make([]byte, length-8)
+	if _, err := io.ReadFull(p.r, block); err != nil {
+		return 0, nil, err
+	}
+	// strip the trailing copy of the block length
+	return blockType, block[:len(block)-4], nil
+}
+
+func (p *PcapReader) readSectionHeader() error {
+	header, err := p.r.Peek(12)
+	if err != nil {
+		return err
+	}
+	switch {
+	case binary.LittleEndian.Uint32(header[8:12]) == pcapngByteOrderMagic:
+		p.byteOrder = binary.LittleEndian
+	case binary.BigEndian.Uint32(header[8:12]) == pcapngByteOrderMagic:
+		p.byteOrder = binary.BigEndian
+	default:
+		return ErrPcapFormat
+	}
+	// interface IDs are scoped to a section
+	p.interfaces = nil
+	_, _, err = p.readBlock()
+	return err
+}
+
+func (p *PcapReader) nextBlock() (*Packet, error) {
+	for {
+		magic, err := p.r.Peek(4)
+		if err != nil {
+			return nil, err
+		}
+		if binary.BigEndian.Uint32(magic) == pcapngSectionHeader {
+			if err := p.readSectionHeader(); err != nil {
+				return nil, err
+			}
+			continue
+		}
+
+		blockType, body, err := p.readBlock()
+		if err != nil {
+			return nil, err
+		}
+		switch blockType {
+		case pcapngInterface:
+			if len(body) < 8 {
+				return nil, ErrPcapFormat
+			}
+			p.interfaces = append(p.interfaces, pcapngInterfaceInfo{
+				linkType:   uint32(p.byteOrder.Uint16(body[0:2])),
+				resolution: p.interfaceResolution(body[8:]),
+			})
+		case pcapngEnhancedPacket:
+			if len(body) < 20 {
+				return nil, ErrPcapFormat
+			}
+			id := p.byteOrder.Uint32(body[0:4])
+			if int(id) >= len(p.interfaces) {
+				return nil, ErrPcapFormat
+			}
+			captured := p.byteOrder.Uint32(body[12:16])
+			if int(captured) > len(body)-20 {
+				return nil, ErrPcapFormat
+			}
+			ticks := uint64(p.byteOrder.Uint32(body[4:8]))<<32 | uint64(p.byteOrder.Uint32(body[8:12]))
+			packet := &Packet{
+				Timestamp: pcapngTimestamp(ticks, p.interfaces[id].resolution),
+				Length:    int(p.byteOrder.Uint32(body[16:20])),
+				LinkType:  p.interfaces[id].linkType,
+				Data:      body[20 : 20+captured],
+			}
+			packet.decode()
+			return packet, nil
+		case pcapngSimplePacket:
+			if len(body) < 4 || len(p.interfaces) == 0 {
+				return nil, ErrPcapFormat
+			}
+			length := int(p.byteOrder.Uint32(body[0:4]))
+			data := body[4:]
+			if length < len(data) {
+				data = data[:length]
+			}
+			packet := &Packet{
+				Length:   length,
+				LinkType: p.interfaces[0].linkType,
+				Data:     data,
+			}
+			packet.decode()
+			return packet, nil
+		}
+	}
+}
+
+// interfaceResolution returns the duration of a timestamp tick in seconds, as
+// given by the if_tsresol option of an interface description block
+func (p *PcapReader) interfaceResolution(options []byte) float64 {
+	for len(options) >= 4 {
+		code := p.byteOrder.Uint16(options[0:2])
+		length := int(p.byteOrder.Uint16(options[2:4]))
+		if code == 0 || len(options) < 4+length {
+			break
+		}
+		if code == 9 && length == 1 {
+			exponent := float64(options[4] & 0x7f)
+			if options[4]&0x80 != 0 {
+				return math.Pow(2, -exponent)
+			}
+			return math.Pow(10, -exponent)
+		}
+		// the options are padded to 32 bits, a missing padding ends them
+		if len(options) < 4+(length+3)&^3 {
+			break
+		}
+		options = options[4+(length+3)&^3:]
+	}
+	return 1e-6
+}
+
+func pcapngTimestamp(ticks uint64, resolution float64) time.Time {
+	ticksPerSecond := uint64(math.Round(1 / resolution))
+	if ticksPerSecond == 0 {
+		return time.Time{}
+	}
+	seconds := ticks / ticksPerSecond
+	nanoseconds := float64(ticks%ticksPerSecond) * resolution * 1e9
+	return time.Unix(int64(seconds), int64(nanoseconds))
+}
+
+// decode decodes the headers of the packet as far as it can go
+func (pkt *Packet) decode() {
+	pkt.Payload = pkt.Data
+	if pkt.LinkType != LinkTypeEthernet {
+		return
+	}
+
+	data := pkt.Data
+	if len(data) < 14 {
+		return
+	}
+	pkt.Ethernet = &EthernetHeader{
+		Destination: net.HardwareAddr(data[0:6]),
+		Source:      net.HardwareAddr(data[6:12]),
+		EtherType:   binary.BigEndian.Uint16(data[12:14]),
+	}
+	data = data[14:]
+	if pkt.Ethernet.EtherType == EtherTypeVLAN {
+		if len(data) < 4 {
+			return
+		}
+		pkt.Ethernet.VLAN = binary.BigEndian.Uint16(data[0:2]) & 0x0fff
+		pkt.Ethernet.EtherType = binary.BigEndian.Uint16(data[2:4])
+		data = data[4:]
+	}
+	pkt.Payload = data
+
+	switch pkt.Ethernet.EtherType {
+	case EtherTypeARP:
+		pkt.decodeARP(data)
+	case EtherTypeIPv4:
+		pkt.decodeIPv4(data)
+	case EtherTypeIPv6:
+		pkt.decodeIPv6(data)
+	}
+}
+
+func (pkt *Packet) decodeARP(data []byte) {
+	if len(data) < 8 {
+		return
+	}
+	hlen, plen := int(data[4]), int(data[5])
+	if len(data) < 8+2*hlen+2*plen {
+		return
+	}
+	pkt.ARP = &ARPHeader{
+		HardwareType:          binary.BigEndian.Uint16(data[0:2]),
+		ProtocolType:          binary.BigEndian.Uint16(data[2:4]),
+		Operation:             binary.BigEndian.Uint16(data[6:8]),
+		SenderHardwareAddress: net.HardwareAddr(data[8 : 8+hlen]),
+		SenderProtocolAddress: net.IP(data[8+hlen : 8+hlen+plen]),
+		TargetHardwareAddress: net.HardwareAddr(data[8+hlen+plen : 8+2*hlen+plen]),
+		TargetProtocolAddress: net.IP(data[8+2*hlen+plen : 8+2*hlen+2*plen]),
+	}
+	pkt.Payload = data[8+2*hlen+2*plen:]
+}
+
+func (pkt *Packet) decodeIPv4(data []byte) {
+	if len(data) < 20 {
+		return
+	}
+	ihl := data[0] & 0x0f
+	if ihl < 5 || len(data) < int(ihl)*4 {
+		return
+	}
+	pkt.IPv4 = &IPv4Header{
+		Version:        data[0] >> 4,
+		IHL:            ihl,
+		TOS:            data[1],
+		TotalLength:    binary.BigEndian.Uint16(data[2:4]),
+		ID:             binary.BigEndian.Uint16(data[4:6]),
+		Flags:          data[6] >> 5,
+		FragmentOffset: binary.BigEndian.Uint16(data[6:8]) & 0x1fff,
+		TTL:            data[8],
+		Protocol:       data[9],
+		Checksum:       binary.BigEndian.Uint16(data[10:12]),
+		Source:         net.IP(data[12:16]),
+		Destination:    net.IP(data[16:20]),
+		Options:        data[20 : ihl*4],
+	}
+	data = data[ihl*4:]
+	// drop the Ethernet padding of short frames
+	if length := int(pkt.IPv4.TotalLength) - int(ihl)*4; length >= 0 && length < len(data) {
+		data = data[:length]
+	}
+	pkt.Payload = data
+
+	if pkt.IPv4.Protocol == IPProtocolICMP && pkt.IPv4.FragmentOffset == 0 {
+		pkt.decodeICMP(data)
+	}
+}
+
+func (pkt *Packet) decodeIPv6(data []byte) {
+	if len(data) < 40 {
+		return
+	}
+	pkt.IPv6 = &IPv6Header{
+		Version:       data[0] >> 4,
+		TrafficClass:  uint8(binary.BigEndian.Uint16(data[0:2]) >> 4),
+		FlowLabel:     binary.BigEndian.Uint32(data[0:4]) & 0x000fffff,
+		PayloadLength: binary.BigEndian.Uint16(data[4:6]),
+		NextHeader:    data[6],
+		HopLimit:      data[7],
+		Source:        net.IP(data[8:24]),
+		Destination:   net.IP(data[24:40]),
+	}
+	data = data[40:]
+	if length := int(pkt.IPv6.PayloadLength); length < len(data) {
+		data = data[:length]
+	}
+	pkt.Payload = data
+
+	if pkt.IPv6.NextHeader == IPProtocolICMPv6 {
+		pkt.decodeICMP(data)
+	}
+}
+
+func (pkt *Packet) decodeICMP(data []byte) {
+	if len(data) < 4 {
+		return
+	}
+	pkt.ICMP = &ICMPHeader{
+		Type:     data[0],
+		Code:     data[1],
+		Checksum: binary.BigEndian.Uint16(data[2:4]),
+	}
+	pkt.Payload = data[4:]
+}
@@ -0,0 +1,218 @@
+package gogns3
+
+import (
+	"bytes"
+	"encoding/binary"
+	"io"
+	"net"
+	"testing"
+)
+
+var (
+	testPcapMac1 = []byte{0x0c, 0x00, 0x00, 0x00, 0x00, 0x01}
+	testPcapMac2 = []byte{0x0c, 0x00, 0x00, 0x00, 0x00, 0x02}
+)
+
+func testPcapEthernet(etherType uint16, payload []byte) []byte {
+	frame := append([]byte{}, testPcapMac2...)
+	frame = append(frame, testPcapMac1...)
+	frame = append(frame, byte(etherType>>8), byte(etherType))
+	return append(frame, payload...)
+}
+
+func testPcapIPv4(protocol uint8, payload []byte) []byte {
+	header := []byte{
+		0x45, 0xc0, 0, 0, 0x12, 0x34, 0x40, 0x00, 1, protocol, 0, 0,
+		10, 0, 0, 1,
+		224, 0, 0, 5,
+	}
+	binary.BigEndian.PutUint16(header[2:4], uint16(20+len(payload)))
+	return append(header, payload...)
+}
+
+func testPcapFile(frames ...[]byte) []byte {
+	b := &bytes.Buffer{}
+	binary.Write(b, binary.LittleEndian, []uint32{0xa1b2c3d4, 0x00040002, 0, 0, 65535, LinkTypeEthernet})
+	for idx, frame := range frames {
+		binary.Write(b, binary.LittleEndian, []uint32{1600000000 + uint32(idx), 500, uint32(len(frame)), uint32(len(frame))})
+		b.Write(frame)
+	}
+	return b.Bytes()
+}
+
+func testPcapngBlock(blockType uint32, body []byte) []byte {
+	for len(body)%4 != 0 {
+		body = append(body, 0)
+	}
+	b := &bytes.Buffer{}
+	binary.Write(b, binary.BigEndian, []uint32{blockType, uint32(len(body) + 12)})
+	b.Write(body)
+	binary.Write(b, binary.BigEndian, uint32(len(body)+12))
+	return b.Bytes()
+}
+
+func TestPcapReaderPcap(t *testing.T) {
+	arp := []byte{0, 1, 8, 0, 6, 4, 0, 1}
+	arp = append(arp, testPcapMac1...)
+	arp = append(arp, 10, 0, 0, 1)
+	arp = append(arp, 0, 0, 0, 0, 0, 0)
+	arp = append(arp, 10, 0, 0, 2)
+	ospfHello := []byte{2, 1, 0, 44}
+	icmpEcho := []byte{8, 0, 0xf7, 0xff, 0, 1, 0, 1}
+
+	p, err := NewPcapReader(bytes.NewReader(testPcapFile(
+		testPcapEthernet(EtherTypeARP, arp),
+		testPcapEthernet(EtherTypeIPv4, testPcapIPv4(IPProtocolOSPF, ospfHello)),
+		testPcapEthernet(EtherTypeIPv4, append(testPcapIPv4(IPProtocolICMP, icmpEcho), 0, 0, 0)),
+	)))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	pkt, err := p.Next()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if pkt.Timestamp.Unix() != 1600000000 || pkt.Timestamp.Nanosecond() != 500000 {
+		t.Errorf("This packet seems to be misdecoded (timestamp = %v)", pkt.Timestamp)
+	}
+	if pkt.Ethernet == nil || pkt.Ethernet.Source.String() != "0c:00:00:00:00:01" {
+		t.Error("This packet seems to be misdecoded (ethernet source != 0c:00:00:00:00:01)")
+	}
+	if pkt.ARP == nil {
+		t.Fatal("This packet seems to be misdecoded (no ARP header)")
+	}
+	if pkt.ARP.Operation != 1 {
+		t.Error("This packet seems to be misdecoded (ARP operation != 1)")
+	}
+	if !pkt.ARP.TargetProtocolAddress.Equal(net.IPv4(10, 0, 0, 2)) {
+		t.Error("This packet seems to be misdecoded (ARP target != 10.0.0.2)")
+	}
+
+	pkt, err = p.Next()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if pkt.IPv4 == nil {
+		t.Fatal("This packet seems to be misdecoded (no IPv4 header)")
+	}
+	if pkt.IPv4.Protocol != IPProtocolOSPF {
+		t.Error("This packet seems to be misdecoded (IPv4 protocol != OSPF)")
+	}
+	if !pkt.IPv4.Destination.Equal(net.IPv4(224, 0, 0, 5)) {
+		t.Error("This packet seems to be misdecoded (IPv4 destination != 224.0.0.5)")
+	}
+	if !bytes.Equal(pkt.Payload, ospfHello) {
+		t.Error("This packet seems to be misdecoded (payload != OSPF hello)")
+	}
+
+	pkt, err = p.Next()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if pkt.ICMP == nil || pkt.ICMP.Type != 8 {
+		t.Error("This packet seems to be misdecoded (ICMP type != 8)")
+	}
+	if len(pkt.Payload) != 4 {
+		t.Error("This packet seems to be misdecoded (Ethernet padding not removed)")
+	}
+
+	if _, err := p.Next(); err != io.EOF {
+		t.Error("The end of the capture must be reported as io.EOF")
+	}
+}
+
+func TestPcapReaderPcapng(t *testing.T) {
+	ipv6 := make([]byte, 40)
+	ipv6[0] = 0x60
+	binary.BigEndian.PutUint16(ipv6[4:6], 8)
+	ipv6[6] = IPProtocolICMPv6
+	ipv6[7] = 255
+	copy(ipv6[8:24], net.ParseIP("fe80::1"))
+	copy(ipv6[24:40], net.ParseIP("ff02::1"))
+	ipv6 = append(ipv6, 128, 0, 0, 0, 0, 1, 0, 1)
+	frame := testPcapEthernet(EtherTypeVLAN, append([]byte{0, 10, 0x86, 0xdd}, ipv6...))
+
+	b := &bytes.Buffer{}
+	b.Write(testPcapngBlock(0x0a0d0d0a, []byte{0x1a, 0x2b, 0x3c, 0x4d, 0, 1, 0, 0, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff}))
+	b.Write(testPcapngBlock(1, []byte{0, 1, 0, 0, 0, 0, 0xff, 0xff, 0, 9, 0, 1, 9, 0, 0, 0, 0, 0, 0, 0}))
+	epb := &bytes.Buffer{}
+	binary.Write(epb, binary.BigEndian, []uint32{0, 0, 1500000000, uint32(len(frame)), uint32(len(frame))})
+	epb.Write(frame)
+	b.Write(testPcapngBlock(6, epb.Bytes()))
+
+	p, err := NewPcapReader(b)
+	if err != nil {
+		t.Fatal(err)
+	}
+	pkt, err := p.Next()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if pkt.Timestamp.Unix() != 1 || pkt.Timestamp.Nanosecond() != 500000000 {
+		t.Errorf("This packet seems to be misdecoded (timestamp = %v)", pkt.Timestamp)
+	}
+	if pkt.Ethernet == nil || pkt.Ethernet.VLAN != 10 {
+		t.Error("This packet seems to be misdecoded (VLAN != 10)")
+	}
+	if pkt.IPv6 == nil {
+		t.Fatal("This packet seems to be misdecoded (no IPv6 header)")
+	}
+	if !pkt.IPv6.Destination.Equal(net.ParseIP("ff02::1")) {
+		t.Error("This packet seems to be misdecoded (IPv6 destination != ff02::1)")
+	}
+	if pkt.ICMP == nil || pkt.ICMP.Type != 128 {
+		t.Error("This packet seems to be misdecoded (ICMPv6 type != 128)")
+	}
+
+	if _, err := p.Next(); err != io.EOF {
+		t.Error("The end of the capture must be reported as io.EOF")
+	}
+}
+
+func TestPcapReaderError(t *testing.T) {
+	if _, err := NewPcapReader(bytes.NewReader([]byte("this is not a capture"))); err != ErrPcapFormat {
+		t.Error("An invalid capture must be reported as ErrPcapFormat")
+	}
+
+	// records longer than the snaplen, or than any sane packet, are rejected
+	// before their data is allocated
+	for _, length := range []uint32{65536, 0xfffffff0} {
+		b := testPcapFile()
+		b = append(b, make([]byte, 16)...)
+		binary.LittleEndian.PutUint32(b[len(b)-8:], length)
+		p, err := NewPcapReader(bytes.NewReader(b))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := p.Next(); err != ErrPcapFormat {
+			t.Errorf("A record of %d bytes must be reported as ErrPcapFormat (%v)", length, err)
+		}
+	}
+}
+
+func TestPcapReaderPcapngCorrupt(t *testing.T) {
+	shb := testPcapngBlock(0x0a0d0d0a, []byte{0x1a, 0x2b, 0x3c, 0x4d, 0, 1, 0, 0, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff})
+
+	// an interface block whose option is not padded, its length is not a
+	// multiple of 32 bits
+	b := &bytes.Buffer{}
+	b.Write(shb)
+	body := []byte{0, 1, 0, 0, 0, 0, 0xff, 0xff, 0, 2, 0, 1, 'x'}
+	binary.Write(b, binary.BigEndian, []uint32{1, uint32(len(body) + 12)})
+	b.Write(body)
+	binary.Write(b, binary.BigEndian, uint32(len(body)+12))
+
+	p, err := NewPcapReader(b)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := p.Next(); err != ErrPcapFormat {
+		t.Errorf("A block of unaligned length must be reported as ErrPcapFormat (%v)", err)
+	}
+
+	// the options themselves must not be read past their end
+	if r := p.interfaceResolution([]byte{0, 2, 0, 1, 'x'}); r != 1e-6 {
+		t.Errorf("An unpadded option must end the options (resolution = %v)", r)
+	}
+}
@@ -0,0 +1,487 @@
+package gogns3
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+)
+
+// PlanAction is the action of a planned change
+type PlanAction string
+
+// Actions of the planned changes
+const (
+	PlanCreate PlanAction = "create"
+	PlanUpdate PlanAction = "update"
+	PlanDelete PlanAction = "delete"
+)
+
+// PlanChange is a change to be done on the server to converge a project to
+// its topology. Kind is one of project, node, link or drawing. Fields lists
+// the fields which differ for an update.
+type PlanChange struct {
+	Action PlanAction
+	Kind   string
+	Name   string
+	Fields []string
+	apply  func() error
+}
+
+// Plan is the ordered list of changes needed to converge a project to a
+// topology. It is built by Topology.Plan() and executed by Plan.Apply().
+type Plan struct {
+	Changes  []PlanChange
+	Project  *Project
+	Topology *Topology
+	nodeIDs  map[string]string
+}
+
+// Plan compares the topology with the project of the same name on the server
+// and returns the changes needed to converge the project to the topology.
+// Nodes are matched by name, links by their endpoints and drawings by their
+// content. A node whose type or compute changes is deleted and created again.
+func (t *Topology) Plan(s *Server) (*Plan, error) {
+	if err := t.Validate(); err != nil {
+		return nil, err
+	}
+
+	p := &Project{
+		Name:   t.Name,
+		Server: s,
+	}
+	plan := &Plan{
+		Project:  p,
+		Topology: t,
+		nodeIDs:  map[string]string{},
+	}
+
+	// only a missing project is created, any other error (e.g. an ambiguous
+	// name or an unreachable server) is returned
+	err := p.Read()
+	serverError := &ServerError{}
+	if errors.As(err, &serverError) && serverError.Status == 404 {
+		plan.Changes = append(plan.Changes, PlanChange{
+			Action: PlanCreate,
+			Kind:   "project",
+			Name:   t.Name,
+			apply:  p.Create,
+		})
+		plan.diff(nil, nil, nil)
+		return plan, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	nodes, err := p.GetNodes()
+	if err != nil {
+		return nil, err
+	}
+	// the nodes are matched by name, a duplicate name cannot be converged
+	if err := checkNodeNames(nodes); err != nil {
+		return nil, err
+	}
+	links, err := p.GetLinks()
+	if err != nil {
+		return nil, err
+	}
+	drawings, err := p.GetDrawings()
+	if err != nil {
+		return nil, err
+	}
+	plan.diff(nodes, links, drawings)
+	return plan, nil
+}
+
+// Apply executes the changes of the plan in order. It stops at the first
+// error, the changes already applied are not rolled back.
+func (p *Plan) Apply() error {
+	for _, change := range p.Changes {
+		if err := change.apply(); err != nil {
+			return fmt.Errorf("could not %s %s %s: %w", change.Action, change.Kind, change.Name, err)
+		}
+	}
+	return nil
+}
+
+// Empty tells whether the project has already converged to the topology
+func (p *Plan) Empty() bool {
+	return len(p.Changes) == 0
+}
+
+// String returns a readable version of the plan, one change per line
+func (p *Plan) String() string {
+	b := &strings.Builder{}
+	count := map[PlanAction]int{}
+	for _, change := range p.Changes {
+		count[change.Action]++
+		switch change.Action {
+		case PlanCreate:
+			b.WriteString("+ ")
+		case PlanUpdate:
+			b.WriteString("~ ")
+		case PlanDelete:
+			b.WriteString("- ")
+		}
+		b.WriteString(change.Kind + " " + change.Name)
+		if len(change.Fields) > 0 {
+			b.WriteString(" (" + strings.Join(change.Fields, ", ") + ")")
+		}
+		b.WriteString("\n")
+	}
+	fmt.Fprintf(b, "Plan: %d to create, %d to update, %d to delete\n", count[PlanCreate], count[PlanUpdate], count[PlanDelete])
+	return b.String()
+}
+
+// Apply converges the project of the same name on the server to the
+// topology, creating the project if it does not exist. See Topology.Plan().
+func (t *Topology) Apply(s *Server) (*Project, error) {
+	plan, err := t.Plan(s)
+	if err != nil {
+		return nil, err
+	}
+	return plan.Project, plan.Apply()
+}
+
+// diff computes the changes between the topology and the current state of
+// the project
+func (p *Plan) diff(nodes []Node, links []Link, drawings []Drawing) {
+	t := p.Topology
+	deletes := []PlanChange{}
+	creates := []PlanChange{}
+	updates := []PlanChange{}
+
+	// nodes, matched by name
+	current := map[string]Node{}
+	names := map[string]string{}
+	for _, n := range nodes {
+		current[n.Name] = n
+		names[n.UUID] = n.Name
+	}
+	desired := map[string]bool{}
+	replaced := map[string]bool{}
+	for idx := range t.Nodes {
+		tn := &t.Nodes[idx]
+		desired[tn.Name] = true
+		n, ok := current[tn.Name]
+		if ok && (n.NodeType != tn.NodeType || (tn.ComputeID != "" && n.ComputeID != tn.ComputeID)) {
+			replaced[tn.Name] = true
+			deletes = append(deletes, p.deleteNode(n))
+			ok = false
+		}
+		if !ok {
+			creates = append(creates, p.createNode(tn))
+			continue
+		}
+		p.nodeIDs[n.Name] = n.UUID
+		if fields := nodeDiff(tn, &n); len(fields) > 0 {
+			updates = append(updates, p.updateNode(tn, n, fields))
+		}
+	}
+	for _, n := range nodes {
+		if !desired[n.Name] {
+			deletes = append(deletes, p.deleteNode(n))
+		}
+	}
+
+	// links, matched by their endpoints
+	currentLinks := map[string]Link{}
+	for _, l := range links {
+		key, endpoints := linkKey(l, names)
+		for _, e := range endpoints {
+			if replaced[e.Node] || !desired[e.Node] {
+				// the link is deleted along with its node
+				key = ""
+			}
+		}
+		if key != "" {
+			currentLinks[key] = l
+		}
+	}
+	desiredLinks := map[string]bool{}
+	for idx := range t.Links {
+		tl := &t.Links[idx]
+		key := topologyLinkKey(tl)
+		desiredLinks[key] = true
+		l, ok := currentLinks[key]
+		if !ok {
+			creates = append(creates, p.createLink(tl, key))
+			continue
+		}
+		if fields := linkDiff(tl, &l); len(fields) > 0 {
+			updates = append(updates, p.updateLink(tl, l, key, fields))
+		}
+	}
+	linkDeletes := []PlanChange{}
+	for _, l := range links {
+		key, _ := linkKey(l, names)
+		if _, ok := currentLinks[key]; ok && !desiredLinks[key] {
+			linkDeletes = append(linkDeletes, p.deleteLink(l, key))
+		}
+	}
+	sort.Slice(linkDeletes, func(i, j int) bool { return linkDeletes[i].Name < linkDeletes[j].Name })
+
+	// drawings, matched by their content
+	unmatched := append([]Drawing{}, drawings...)
+	for idx := range t.Drawings {
+		td := &t.Drawings[idx]
+		found := false
+		for i, d := range unmatched {
+			if td.drawing(nil) == (Drawing{Locked: d.Locked, Rotation: d.Rotation, SVG: d.SVG, X: d.X, Y: d.Y, Z: d.Z}) {
+				unmatched = append(unmatched[:i], unmatched[i+1:]...)
+				found = true
+				break
+			}
+		}
+		if !found {
+			creates = append(creates, p.createDrawing(td))
+		}
+	}
+	for _, d := range unmatched {
+		deletes = append(deletes, p.deleteDrawing(d))
+	}
+
+	// order the changes so that links are handled after their nodes
+	p.Changes = append(p.Changes, linkDeletes...)
+	p.Changes = append(p.Changes, deletes...)
+	p.Changes = append(p.Changes, creates...)
+	p.Changes = append(p.Changes, updates...)
+	sort.SliceStable(p.Changes, func(i, j int) bool {
+		return planOrder(p.Changes[i]) < planOrder(p.Changes[j])
+	})
+}
+
+// planOrder returns the rank of a change: project creation, deletions of
+// links, drawings and nodes, creations and updates of nodes, then links and
+// drawings
+func planOrder(c PlanChange) int {
+	order := []string{
+		"create project",
+		"delete link", "delete drawing", "delete node",
+		"create node", "update node",
+		"create link", "update link",
+		"create drawing",
+	}
+	for idx, s := range order {
+		if s == string(c.Action)+" "+c.Kind {
+			return idx
+		}
+	}
+	return len(order)
+}
+
+// nodeDiff returns the fields of the node which differ from the topology.
+// Empty optional fields of the topology are left to the server defaults.
+func nodeDiff(tn *TopologyNode, n *Node) []string {
+	fields := []string{}
+	if tn.ConsoleType != "" && tn.ConsoleType != n.ConsoleType {
+		fields = append(fields, "console_type")
+	}
+	if tn.Symbol != "" && tn.Symbol != n.Symbol {
+		fields = append(fields, "symbol")
+	}
+	if tn.X != n.X {
+		fields = append(fields, "x")
+	}
+	if tn.Y != n.Y {
+		fields = append(fields, "y")
+	}
+	if tn.Z != n.Z {
+		fields = append(fields, "z")
+	}
+
+	// compare the properties declared in the topology only
+	desired := map[string]interface{}{}
+	actual := map[string]interface{}{}
+	tn.Properties.nodeType = tn.NodeType
+	n.Properties.nodeType = tn.NodeType
+	b, _ := json.Marshal(tn.Properties)
+	json.Unmarshal(b, &desired)
+	b, _ = json.Marshal(n.Properties)
+	json.Unmarshal(b, &actual)
+	for key, value := range desired {
+		if !reflect.DeepEqual(value, actual[key]) {
+			fields = append(fields, "properties")
+			break
+		}
+	}
+	return fields
+}
+
+// linkDiff returns the fields of the link which differ from the topology
+func linkDiff(tl *TopologyLink, l *Link) []string {
+	fields := []string{}
+	if tl.Suspend != l.Suspend {
+		fields = append(fields, "suspend")
+	}
+	desired, _ := json.Marshal(tl.Filters)
+	actual, _ := json.Marshal(l.Filters)
+	if tl.Filters == nil {
+		desired = []byte("{}")
+	}
+	if l.Filters == nil {
+		actual = []byte("{}")
+	}
+	if string(desired) != string(actual) {
+		fields = append(fields, "filters")
+	}
+	return fields
+}
+
+// linkKey returns the key identifying a link of the project by its endpoints,
+// which are sorted so that the key does not depend on the link direction
+func linkKey(l Link, names map[string]string) (string, []LinkEndpoint) {
+	endpoints := []LinkEndpoint{}
+	for _, ln := range l.Nodes {
+		endpoints = append(endpoints, LinkEndpoint{
+			Node:          names[ln.NodeID],
+			AdapterNumber: ln.AdapterNumber,
+			PortNumber:    ln.PortNumber,
+		})
+	}
+	return endpointsKey(endpoints), endpoints
+}
+
+// topologyLinkKey returns the key identifying a link of the topology
+func topologyLinkKey(tl *TopologyLink) string {
+	endpoints := []LinkEndpoint{}
+	for _, s := range tl.Endpoints {
+		e, _ := ParseLinkEndpoint(s)
+		endpoints = append(endpoints, e)
+	}
+	return endpointsKey(endpoints)
+}
+
+func endpointsKey(endpoints []LinkEndpoint) string {
+	s := []string{}
+	for _, e := range endpoints {
+		s = append(s, e.String())
+	}
+	sort.Strings(s)
+	return strings.Join(s, " <-> ")
+}
+
+func (p *Plan) createNode(tn *TopologyNode) PlanChange {
+	return PlanChange{
+		Action: PlanCreate,
+		Kind:   "node",
+		Name:   tn.Name,
+		apply: func() error {
+			n := tn.node(p.Project)
+			if err := n.Create(); err != nil {
+				return err
+			}
+			p.nodeIDs[n.Name] = n.UUID
+			return nil
+		},
+	}
+}
+
+func (p *Plan) updateNode(tn *TopologyNode, n Node, fields []string) PlanChange {
+	return PlanChange{
+		Action: PlanUpdate,
+		Kind:   "node",
+		Name:   tn.Name,
+		Fields: fields,
+		apply: func() error {
+			n.Project = p.Project
+			if tn.ConsoleType != "" {
+				n.ConsoleType = tn.ConsoleType
+			}
+			if tn.Symbol != "" {
+				n.Symbol = tn.Symbol
+			}
+			n.X, n.Y, n.Z = tn.X, tn.Y, tn.Z
+			// overwrite the properties declared in the topology only
+			tn.Properties.nodeType = tn.NodeType
+			b, _ := json.Marshal(tn.Properties)
+			json.Unmarshal(b, &n.Properties)
+			return n.Update()
+		},
+	}
+}
+
+func (p *Plan) deleteNode(n Node) PlanChange {
+	return PlanChange{
+		Action: PlanDelete,
+		Kind:   "node",
+		Name:   n.Name,
+		apply: func() error {
+			n.Project = p.Project
+			return n.Delete()
+		},
+	}
+}
+
+func (p *Plan) createLink(tl *TopologyLink, key string) PlanChange {
+	return PlanChange{
+		Action: PlanCreate,
+		Kind:   "link",
+		Name:   key,
+		apply: func() error {
+			l, err := tl.link(p.Project, p.nodeIDs)
+			if err != nil {
+				return err
+			}
+			return l.Create()
+		},
+	}
+}
+
+func (p *Plan) updateLink(tl *TopologyLink, l Link, key string, fields []string) PlanChange {
+	return PlanChange{
+		Action: PlanUpdate,
+		Kind:   "link",
+		Name:   key,
+		Fields: fields,
+		apply: func() error {
+			l.Project = p.Project
+			l.Suspend = tl.Suspend
+			l.Filters = tl.Filters
+			if l.Filters == nil {
+				// an empty structure removes the filters
+				l.Filters = &LinkFilters{}
+			}
+			return l.Update()
+		},
+	}
+}
+
+func (p *Plan) deleteLink(l Link, key string) PlanChange {
+	return PlanChange{
+		Action: PlanDelete,
+		Kind:   "link",
+		Name:   key,
+		apply: func() error {
+			l.Project = p.Project
+			return l.Delete()
+		},
+	}
+}
+
+func (p *Plan) createDrawing(td *TopologyDrawing) PlanChange {
+	return PlanChange{
+		Action: PlanCreate,
+		Kind:   "drawing",
+		Name:   fmt.Sprintf("at %d,%d", td.X, td.Y),
+		apply: func() error {
+			d := td.drawing(p.Project)
+			return d.Create()
+		},
+	}
+}
+
+func (p *Plan) deleteDrawing(d Drawing) PlanChange {
+	return PlanChange{
+		Action: PlanDelete,
+		Kind:   "drawing",
+		Name:   fmt.Sprintf("at %d,%d", d.X, d.Y),
+		apply: func() error {
+			d.Project = p.Project
+			return d.Delete()
+		},
+	}
+}
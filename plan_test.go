@@ -0,0 +1,175 @@
+package gogns3
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func testPlanDiff(t *testing.T, topology string, nodes []Node, links []Link, drawings []Drawing) *Plan {
+	topo, err := ParseTopology([]byte(topology))
+	if err != nil {
+		t.Fatal(err)
+	}
+	plan := &Plan{
+		Project:  &Project{Name: topo.Name},
+		Topology: topo,
+		nodeIDs:  map[string]string{},
+	}
+	plan.diff(nodes, links, drawings)
+	return plan
+}
+
+func TestPlanDiff(t *testing.T) {
+	nodes := []Node{
+		{Name: "SW1", NodeType: "ethernet_switch", ComputeID: "local", UUID: "1"},
+		{Name: "PC1", NodeType: "vpcs", ComputeID: "local", UUID: "2", X: -100},
+		{Name: "PC2", NodeType: "qemu", ComputeID: "local", UUID: "3", X: 100},
+		{Name: "PC3", NodeType: "vpcs", ComputeID: "local", UUID: "4"},
+	}
+	links := []Link{
+		{UUID: "a", Nodes: []LinkNode{{NodeID: "2", AdapterNumber: 0, PortNumber: 0}, {NodeID: "1", AdapterNumber: 0, PortNumber: 1}}},
+		{UUID: "b", Nodes: []LinkNode{{NodeID: "1", AdapterNumber: 0, PortNumber: 2}, {NodeID: "3", AdapterNumber: 0, PortNumber: 0}}},
+		{UUID: "c", Nodes: []LinkNode{{NodeID: "4", AdapterNumber: 0, PortNumber: 0}, {NodeID: "1", AdapterNumber: 0, PortNumber: 3}}},
+		{UUID: "d", Nodes: []LinkNode{{NodeID: "2", AdapterNumber: 0, PortNumber: 1}, {NodeID: "1", AdapterNumber: 0, PortNumber: 4}}},
+	}
+	drawings := []Drawing{
+		{UUID: "e", SVG: `<svg height="50" width="100"><rect height="50" width="100" /></svg>`, X: 0, Y: 100},
+		{UUID: "f", SVG: "<svg />", X: 1, Y: 1},
+	}
+
+	plan := testPlanDiff(t, testTopologyYAML, nodes, links, drawings)
+
+	s := plan.String()
+	if plan.Empty() {
+		t.Fatal("This plan must not be empty")
+	}
+	for _, line := range []string{
+		"- link PC1:0/1 <-> SW1:0/4\n",
+		"- node PC2\n",
+		"- node PC3\n",
+		"- drawing at 1,1\n",
+		"+ node PC2\n",
+		"+ link PC2:0/0 <-> SW1:0/2\n",
+		"Plan: 2 to create, 0 to update, 4 to delete\n",
+	} {
+		if !containsLine(s, line) {
+			t.Errorf("This plan must contain %q:\n%s", line, s)
+		}
+	}
+
+	// node deletions must come after link deletions, node creations before
+	// link creations
+	rank := map[string]int{}
+	for idx, change := range plan.Changes {
+		rank[string(change.Action)+" "+change.Kind] = idx
+	}
+	if rank["delete link"] > rank["delete node"] || rank["create node"] > rank["create link"] {
+		t.Errorf("This plan seems to be misordered:\n%s", s)
+	}
+}
+
+func TestPlanDiffUpdate(t *testing.T) {
+	nodes := []Node{
+		{Name: "SW1", NodeType: "ethernet_switch", ComputeID: "local", UUID: "1"},
+		{Name: "PC1", NodeType: "vpcs", ComputeID: "local", UUID: "2", X: 0},
+		{Name: "PC2", NodeType: "vpcs", ComputeID: "local", UUID: "3", X: 100},
+	}
+	links := []Link{
+		{UUID: "a", Nodes: []LinkNode{{NodeID: "2", AdapterNumber: 0, PortNumber: 0}, {NodeID: "1", AdapterNumber: 0, PortNumber: 1}}, Suspend: true},
+		{UUID: "b", Nodes: []LinkNode{{NodeID: "1", AdapterNumber: 0, PortNumber: 2}, {NodeID: "3", AdapterNumber: 0, PortNumber: 0}}},
+	}
+	drawings := []Drawing{
+		{UUID: "e", SVG: `<svg height="50" width="100"><rect height="50" width="100" /></svg>`, X: 0, Y: 100},
+	}
+
+	plan := testPlanDiff(t, testTopologyYAML, nodes, links, drawings)
+
+	s := plan.String()
+	for _, line := range []string{
+		"~ node PC1 (x)\n",
+		"~ link PC1:0/0 <-> SW1:0/1 (suspend)\n",
+		"~ link PC2:0/0 <-> SW1:0/2 (filters)\n",
+		"Plan: 0 to create, 3 to update, 0 to delete\n",
+	} {
+		if !containsLine(s, line) {
+			t.Errorf("This plan must contain %q:\n%s", line, s)
+		}
+	}
+}
+
+func TestPlanDiffNoChange(t *testing.T) {
+	loss := 10
+	nodes := []Node{
+		{Name: "SW1", NodeType: "ethernet_switch", ComputeID: "local", UUID: "1"},
+		{Name: "PC1", NodeType: "vpcs", ComputeID: "local", UUID: "2", X: -100},
+		{Name: "PC2", NodeType: "vpcs", ComputeID: "local", UUID: "3", X: 100},
+	}
+	links := []Link{
+		{UUID: "a", Nodes: []LinkNode{{NodeID: "1", AdapterNumber: 0, PortNumber: 1}, {NodeID: "2", AdapterNumber: 0, PortNumber: 0}}},
+		{UUID: "b", Nodes: []LinkNode{{NodeID: "1", AdapterNumber: 0, PortNumber: 2}, {NodeID: "3", AdapterNumber: 0, PortNumber: 0}}, Filters: &LinkFilters{PacketLoss: &loss}},
+	}
+	drawings := []Drawing{
+		{UUID: "e", SVG: `<svg height="50" width="100"><rect height="50" width="100" /></svg>`, X: 0, Y: 100},
+	}
+
+	plan := testPlanDiff(t, testTopologyYAML, nodes, links, drawings)
+	if !plan.Empty() {
+		t.Errorf("This plan must be empty:\n%s", plan)
+	}
+}
+
+func containsLine(s string, line string) bool {
+	for _, l := range strings.SplitAfter(s, "\n") {
+		if l == line {
+			return true
+		}
+	}
+	return false
+}
+
+func TestTopologyPlanApply(t *testing.T) {
+	resetTestProject(t)
+
+	topo, _ := ParseTopology([]byte(testTopologyYAML))
+	if _, err := topo.Apply(getTestServer(t)); err != nil {
+		t.Error("Could not apply a topology on an existing project")
+		t.Error(err)
+		return
+	}
+
+	plan, err := topo.Plan(getTestServer(t))
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	if !plan.Empty() {
+		t.Errorf("The project must have converged to the topology:\n%s", plan)
+	}
+}
+
+func TestTopologyPlanErrors(t *testing.T) {
+	s, stop := newTestFindServer()
+	defer stop()
+
+	// a missing project is created
+	plan, err := (&Topology{Name: "lab9"}).Plan(s)
+	if err != nil || len(plan.Changes) != 1 || plan.Changes[0].Action != PlanCreate || plan.Changes[0].Kind != "project" {
+		t.Errorf("A missing project must be created (%+v, %v)", plan, err)
+	}
+
+	// an ambiguous name or an unreachable server must not be read as a
+	// missing project
+	ambiguousError := &AmbiguousError{}
+	if _, err := (&Topology{Name: "lab2"}).Plan(s); !errors.As(err, &ambiguousError) {
+		t.Errorf("Planning a duplicate project name must be ambiguous (%v)", err)
+	}
+	// lab1 has two nodes named SW1
+	if _, err := (&Topology{Name: "lab1"}).Plan(s); !errors.As(err, &ambiguousError) || ambiguousError.Name != "SW1" {
+		t.Errorf("Planning a project with duplicate node names must be ambiguous (%v)", err)
+	}
+	serverError := &ServerError{}
+	if _, err := (&Topology{Name: "lab1"}).Plan(newTestUnreachableServer()); err == nil || errors.As(err, &serverError) {
+		t.Errorf("A connection error must be returned as is (%v)", err)
+	}
+}
@@ -0,0 +1,102 @@
+package gogns3
+
+import (
+	"errors"
+	"fmt"
+)
+
+// NodePort is a port of a node, as described by the server. InUse is only set
+// by GetPorts() and Graph.Ports(), when a link is connected to the port.
+type NodePort struct {
+	AdapterNumber int    `json:"adapter_number"`
+	InUse         bool   `json:"-"`
+	LinkType      string `json:"link_type"`
+	Name          string `json:"name"`
+	PortNumber    int    `json:"port_number"`
+	ShortName     string `json:"short_name,omitempty"`
+}
+
+// ErrNoFreePort is returned when two nodes cannot be connected because they
+// have no free port of the same link type
+var ErrNoFreePort = errors.New("no free compatible port")
+
+// Ports gets the ports of a node of the graph, flagged as in use when a link
+// of the graph is connected to them
+func (g *Graph) Ports(nodeID string) []NodePort {
+	n := g.Node(nodeID)
+	if n == nil {
+		return nil
+	}
+	ports := make([]NodePort, len(n.Ports))
+	copy(ports, n.Ports)
+	for idx := range ports {
+		for _, e := range g.edges[nodeID] {
+			if e.Local.AdapterNumber == ports[idx].AdapterNumber && e.Local.PortNumber == ports[idx].PortNumber {
+				ports[idx].InUse = true
+			}
+		}
+	}
+	return ports
+}
+
+// GetPorts gets the ports of the node from the server, flagged as in use when
+// a link of the project is connected to them
+func (n *Node) GetPorts() ([]NodePort, error) {
+	// Use a new struct because Read() will overwrite it
+	node := Node{
+		Name:    n.Name,
+		Project: n.Project,
+		UUID:    n.UUID,
+	}
+	if err := node.Read(); err != nil {
+		return nil, err
+	}
+	links, err := n.Project.GetLinks()
+	if err != nil {
+		return nil, err
+	}
+	return NewGraph([]Node{node}, links).Ports(node.UUID), nil
+}
+
+// Connect creates a link between two nodes of the project, on the first free
+// port of node a and the first free port of node b of the same link type.
+// Read() may be called on the nodes before a Connect() can be executed. The
+// error is ErrNoFreePort if the nodes have no free compatible ports.
+func (p *Project) Connect(a *Node, b *Node) (*Link, error) {
+	for _, n := range []*Node{a, b} {
+		if n.UUID == "" {
+			if err := n.Read(); err != nil {
+				return nil, err
+			}
+		}
+	}
+	g, err := p.Graph()
+	if err != nil {
+		return nil, err
+	}
+
+	portsB := g.Ports(b.UUID)
+	for _, pa := range g.Ports(a.UUID) {
+		if pa.InUse {
+			continue
+		}
+		for _, pb := range portsB {
+			if pb.InUse || pb.LinkType != pa.LinkType || a.UUID == b.UUID && pb == pa {
+				continue
+			}
+			l := &Link{
+				LinkType: pa.LinkType,
+				Nodes: []LinkNode{
+					{AdapterNumber: pa.AdapterNumber, NodeID: a.UUID, PortNumber: pa.PortNumber},
+					{AdapterNumber: pb.AdapterNumber, NodeID: b.UUID, PortNumber: pb.PortNumber},
+				},
+				Project: p,
+			}
+			if err := l.Create(); err != nil {
+				return nil, err
+			}
+			return l, nil
+		}
+	}
+	return nil, fmt.Errorf("%w between %s and %s", ErrNoFreePort, a.Name, b.Name)
+}
@@ -0,0 +1,94 @@
+package gogns3
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"testing"
+)
+
+// newTestPortsServer returns a server with a project where PC1 is connected to
+// SW1, and R1 has a serial and an Ethernet port. The links created are
+// recorded.
+func newTestPortsServer(created *[]Link) (*Server, func()) {
+	s, stop := newTestHTTPServer(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.URL.Path == "/v2/projects/p1/nodes" && r.Method == "GET":
+			w.Write([]byte(`[
+				{"name": "PC1", "node_id": "pc1", "node_type": "vpcs", "ports": [
+					{"name": "Ethernet0", "short_name": "e0", "adapter_number": 0, "port_number": 0, "link_type": "ethernet"}
+				]},
+				{"name": "R1", "node_id": "r1", "node_type": "dynamips", "ports": [
+					{"name": "Serial0/0", "short_name": "s0/0", "adapter_number": 0, "port_number": 0, "link_type": "serial"},
+					{"name": "Ethernet1/0", "short_name": "e1/0", "adapter_number": 1, "port_number": 0, "link_type": "ethernet"}
+				]},
+				{"name": "SW1", "node_id": "sw1", "node_type": "ethernet_switch", "ports": [
+					{"name": "Ethernet0", "short_name": "e0", "adapter_number": 0, "port_number": 0, "link_type": "ethernet"},
+					{"name": "Ethernet1", "short_name": "e1", "adapter_number": 0, "port_number": 1, "link_type": "ethernet"},
+					{"name": "Ethernet2", "short_name": "e2", "adapter_number": 0, "port_number": 2, "link_type": "ethernet"}
+				]}
+			]`))
+		case r.URL.Path == "/v2/projects/p1/nodes/sw1" && r.Method == "GET":
+			w.Write([]byte(`{"name": "SW1", "node_id": "sw1", "node_type": "ethernet_switch", "ports": [
+				{"name": "Ethernet0", "short_name": "e0", "adapter_number": 0, "port_number": 0, "link_type": "ethernet"},
+				{"name": "Ethernet1", "short_name": "e1", "adapter_number": 0, "port_number": 1, "link_type": "ethernet"}
+			]}`))
+		case r.URL.Path == "/v2/projects/p1/links" && r.Method == "GET":
+			w.Write([]byte(`[
+				{"link_id": "l1", "nodes": [{"node_id": "pc1", "adapter_number": 0, "port_number": 0}, {"node_id": "sw1", "adapter_number": 0, "port_number": 0}]}
+			]`))
+		case r.URL.Path == "/v2/projects/p1/links" && r.Method == "POST":
+			l := Link{}
+			json.NewDecoder(r.Body).Decode(&l)
+			*created = append(*created, l)
+			l.UUID = "l2"
+			w.WriteHeader(http.StatusCreated)
+			json.NewEncoder(w).Encode(l)
+		default:
+			http.NotFound(w, r)
+		}
+	})
+	s.APIVersion = APIv2
+	return s, stop
+}
+
+func TestNodeGetPorts(t *testing.T) {
+	created := []Link{}
+	s, stop := newTestPortsServer(&created)
+	defer stop()
+	n := Node{Project: &Project{Server: s, UUID: "p1"}, UUID: "sw1"}
+
+	ports, err := n.GetPorts()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(ports) != 2 || !ports[0].InUse || ports[1].InUse || ports[1].ShortName != "e1" || ports[1].LinkType != "ethernet" {
+		t.Errorf("These ports seem to be wrong (%+v)", ports)
+	}
+	if n.Ports != nil {
+		t.Error("GetPorts must not modify the node")
+	}
+}
+
+func TestProjectConnect(t *testing.T) {
+	created := []Link{}
+	s, stop := newTestPortsServer(&created)
+	defer stop()
+	p := &Project{Server: s, UUID: "p1"}
+
+	// the switch is read by name, and the serial port of the router skipped
+	sw1, r1 := &Node{Name: "SW1", Project: p}, &Node{Project: p, UUID: "r1"}
+	l, err := p.Connect(sw1, r1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := []LinkNode{{AdapterNumber: 0, NodeID: "sw1", PortNumber: 1}, {AdapterNumber: 1, NodeID: "r1", PortNumber: 0}}
+	if l.UUID != "l2" || l.Project != p || len(created) != 1 || created[0].LinkType != "ethernet" || len(created[0].Nodes) != 2 || created[0].Nodes[0] != expected[0] || created[0].Nodes[1] != expected[1] {
+		t.Errorf("This link seems to be wrong (%+v)", created)
+	}
+
+	pc1 := &Node{Name: "PC1", Project: p, UUID: "pc1"}
+	if _, err := p.Connect(pc1, sw1); !errors.Is(err, ErrNoFreePort) {
+		t.Errorf("PC1 has no free port (%v)", err)
+	}
+}
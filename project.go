@@ -1,7 +1,13 @@
 package gogns3
 
 import (
+	"context"
+	"crypto/rand"
 	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/url"
 )
 
 // Project is the basic structure used for a GNS3 project
@@ -16,27 +22,53 @@ type Project struct {
 	ShowInterfaceLabels bool    `json:"show_interface_labels"`
 	ShowLayers          bool    `json:"show_layers"`
 	SnapToGgrid         bool    `json:"snap_to_grid"`
+	Status              string  `json:"status,omitempty"`
 	Zoom                int     `json:"zoom,omitempty"`
 }
 
-func (p *Project) url() string {
-	return p.Server.url() + string('/') + p.UUID
+// url returns the URL of the project, or of the given path under it
+func (p *Project) url(elements ...string) string {
+	return p.Server.url(append([]string{p.UUID}, elements...)...)
 }
 
-// Read reads an existing project on the server
+// Read reads an existing project on the server, by UUID if it is known, by
+// name otherwise. An AmbiguousError is returned if several projects have the
+// name.
 func (p *Project) Read() error {
-	// save the server information as it will be reset
-	server := p.Server
-	projects, _ := p.Server.GetProjects()
-	for _, project := range projects {
-		if project.Name == p.Name {
-			*p = project
-			// restore the server information
-			p.Server = server
-			return nil
+	if p.UUID != "" {
+		status, content, err := p.Server.HTTPRequest("GET", p.url(), nil)
+		if err != nil {
+			return err
+		}
+		if !(status >= 200 && status < 300) {
+			serverError := ServerError{Status: status}
+			json.Unmarshal(content, &serverError)
+			return &serverError
 		}
+		project := Project{Server: p.Server}
+		if err := json.Unmarshal(content, &project); err != nil {
+			return err
+		}
+		*p = project
+		return nil
+	}
+
+	projects, err := p.Server.FindProjects(ProjectName(Exact(p.Name)))
+	if err != nil {
+		return err
+	}
+	switch len(projects) {
+	case 0:
+		return &ServerError{Status: 404, Message: "Project does not exist on server"}
+	case 1:
+		*p = projects[0]
+		return nil
+	}
+	ambiguousError := &AmbiguousError{Kind: "project", Name: p.Name}
+	for _, project := range projects {
+		ambiguousError.UUIDs = append(ambiguousError.UUIDs, project.UUID)
 	}
-	return &ServerError{Status: 404, Message: "Project does not exist on server"}
+	return ambiguousError
 }
 
 // Exists checks a project exists on the server
@@ -45,6 +77,7 @@ func (p *Project) Exists() (bool, error) {
 	project := Project{
 		Name:   p.Name,
 		Server: p.Server,
+		UUID:   p.UUID,
 	}
 
 	err := project.Read()
@@ -55,52 +88,111 @@ func (p *Project) Exists() (bool, error) {
 func (p *Project) Create() error {
 	b, _ := json.Marshal(p)
 	status, content, err := p.Server.HTTPRequest("POST", p.Server.url(), b)
+	if err != nil {
+		return err
+	}
 	if !(status >= 200 && status < 300) {
-		serverError := ServerError{}
+		serverError := ServerError{Status: status}
 		json.Unmarshal(content, &serverError)
 		return &serverError
 	}
 	json.Unmarshal(content, p)
-	return err
+	return nil
 }
 
 // Delete deletes a project on the server
 // Read() may be called before a Delete() can be executed
 func (p *Project) Delete() error {
 	if p.UUID == "" {
-		p.Read()
+		if err := p.Read(); err != nil {
+			return err
+		}
 	}
 	status, content, err := p.Server.HTTPRequest("DELETE", p.url(), nil)
+	if err != nil {
+		return err
+	}
 	if !(status >= 200 && status < 300) {
-		serverError := ServerError{}
+		serverError := ServerError{Status: status}
 		json.Unmarshal(content, &serverError)
 		return &serverError
 	}
-	return err
+	return nil
 }
 
 // Update updates a project on the server
 func (p *Project) Update() error {
-	var UUID = p.UUID
-	p.UUID = ""
-	b, _ := json.Marshal(p)
+	// the UUID is not sent and the status is set by the server, it cannot be
+	// changed: a copy is sent so that the project is not modified meanwhile
+	project := *p
+	project.UUID = ""
+	project.Status = ""
+	b, _ := json.Marshal(project)
 
-	status, content, err := p.Server.HTTPRequest("PUT", p.url()+UUID, b)
+	status, content, err := p.Server.HTTPRequest("PUT", p.url(), b)
+	if err != nil {
+		return err
+	}
 	if !(status >= 200 && status < 300) {
-		serverError := ServerError{}
+		serverError := ServerError{Status: status}
 		json.Unmarshal(content, &serverError)
 		return &serverError
 	}
 	json.Unmarshal(content, p)
-	return err
+	return nil
+}
+
+// Open opens a project on the server, i.e. loads its nodes and links
+// Read() may be called before an Open() can be executed
+func (p *Project) Open() error {
+	if p.UUID == "" {
+		if err := p.Read(); err != nil {
+			return err
+		}
+	}
+	status, content, err := p.Server.HTTPRequest("POST", p.url("open"), nil)
+	if err != nil {
+		return err
+	}
+	if !(status >= 200 && status < 300) {
+		serverError := ServerError{Status: status}
+		json.Unmarshal(content, &serverError)
+		return &serverError
+	}
+	json.Unmarshal(content, p)
+	return nil
+}
+
+// Close closes a project on the server, i.e. stops and unloads its nodes
+// Read() may be called before a Close() can be executed
+func (p *Project) Close() error {
+	if p.UUID == "" {
+		if err := p.Read(); err != nil {
+			return err
+		}
+	}
+	status, content, err := p.Server.HTTPRequest("POST", p.url("close"), nil)
+	if err != nil {
+		return err
+	}
+	if !(status >= 200 && status < 300) {
+		serverError := ServerError{Status: status}
+		json.Unmarshal(content, &serverError)
+		return &serverError
+	}
+	p.Status = "closed"
+	return nil
 }
 
 // GetNodes gets the list of all nodes of a project
 func (p *Project) GetNodes() ([]Node, error) {
 	// Send the HTTP request and analyze errors and status code
-	status, content, _ := p.Server.HTTPRequest("GET", p.url()+"/nodes", nil)
+	status, content, err := p.Server.HTTPRequest("GET", p.url("nodes"), nil)
+	if err != nil {
+		return nil, err
+	}
 	if !(status >= 200 && status < 300) {
-		serverError := ServerError{}
+		serverError := ServerError{Status: status}
 		json.Unmarshal(content, &serverError)
 		return nil, &serverError
 	}
@@ -119,9 +211,12 @@ func (p *Project) GetNodes() ([]Node, error) {
 // GetLinks gets the list of all links of a project
 func (p *Project) GetLinks() ([]Link, error) {
 	// Send the HTTP request and analyze errors and status code
-	status, content, _ := p.Server.HTTPRequest("GET", p.url()+"/links", nil)
+	status, content, err := p.Server.HTTPRequest("GET", p.url("links"), nil)
+	if err != nil {
+		return nil, err
+	}
 	if !(status >= 200 && status < 300) {
-		serverError := ServerError{}
+		serverError := ServerError{Status: status}
 		json.Unmarshal(content, &serverError)
 		return nil, &serverError
 	}
@@ -136,3 +231,71 @@ func (p *Project) GetLinks() ([]Link, error) {
 
 	return links, nil
 }
+
+// ReadFile reads a file of the project directory. The path is relative to this
+// directory. The caller must close the returned reader. The transfer is
+// cancelled when no data is received within the timeout of the server.
+func (p *Project) ReadFile(path string) (io.ReadCloser, error) {
+	return p.Server.fileStream("GET", p.url("files", path), nil)
+}
+
+// WriteFile writes a file in the project directory. The path is relative to
+// this directory. The transfer is cancelled when no data is sent or received
+// within the timeout of the server.
+func (p *Project) WriteFile(path string, r io.Reader) error {
+	body, err := p.Server.fileStream("POST", p.url("files", path), r)
+	if err != nil {
+		return err
+	}
+	return body.Close()
+}
+
+// GetDrawings gets the list of all drawings of a project
+func (p *Project) GetDrawings() ([]Drawing, error) {
+	// Send the HTTP request and analyze errors and status code
+	status, content, err := p.Server.HTTPRequest("GET", p.url("drawings"), nil)
+	if err != nil {
+		return nil, err
+	}
+	if !(status >= 200 && status < 300) {
+		serverError := ServerError{Status: status}
+		json.Unmarshal(content, &serverError)
+		return nil, &serverError
+	}
+
+	// Unmarshal the JSON-encoded drawing list
+	drawings := []Drawing{}
+	json.Unmarshal(content, &drawings)
+	// Set the project for each drawing
+	for idx := range drawings {
+		drawings[idx].Project = p
+	}
+
+	return drawings, nil
+}
+
+// newUUID returns a random (version 4) UUID
+func newUUID() string {
+	b := make([]byte, 16)
+	rand.Read(b)
+	b[6] = b[6]&0x0f | 0x40
+	b[8] = b[8]&0x3f | 0x80
+	return fmt.Sprintf("%x-%x-%x-%x-%x", b[0:4], b[4:6], b[6:8], b[8:10], b[10:16])
+}
+
+// Import imports a portable project, i.e. a zip archive containing a
+// project.gns3 file, as a new project on the server. A UUID is generated for
+// the project if it has none.
+func (p *Project) Import(r io.Reader) error {
+	if p.UUID == "" {
+		p.UUID = newUUID()
+	}
+	body, err := p.Server.HTTPStream(context.Background(), "POST", p.Server.endpointURL(url.Values{"name": {p.Name}}, "projects", p.UUID, "import"), r)
+	if err != nil {
+		return err
+	}
+	defer body.Close()
+	content, err := ioutil.ReadAll(body)
+	json.Unmarshal(content, p)
+	return err
+}
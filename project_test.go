@@ -1,7 +1,15 @@
 package gogns3
 
 import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"strings"
 	"testing"
+	"time"
 )
 
 func resetTestProject(t *testing.T) *Project {
@@ -208,6 +216,26 @@ func TestProjectDelete(t *testing.T) {
 	}
 }
 
+func TestProjectOpenClose(t *testing.T) {
+	p := *resetTestProject(t)
+
+	if err := p.Close(); err != nil {
+		t.Error("Could not close an existing project")
+		t.Error(err)
+	}
+	if err := p.Read(); err != nil || p.Status != "closed" {
+		t.Error("This project seems to be opened (status != closed)")
+	}
+
+	if err := p.Open(); err != nil {
+		t.Error("Could not open an existing project")
+		t.Error(err)
+	}
+	if p.Status != "opened" {
+		t.Error("This project seems to be closed (status != opened)")
+	}
+}
+
 func TestProjectDeleteError(t *testing.T) {
 	p := *resetTestProject(t)
 	p.UUID = "11111111-1111-1111-1111-111111111111"
@@ -273,3 +301,96 @@ func TestProjectGetLinksError(t *testing.T) {
 		}
 	}
 }
+
+func TestProjectWriteReadFile(t *testing.T) {
+	p := *resetTestProject(t)
+
+	if err := p.WriteFile("notes/README.txt", strings.NewReader("gogns3 lab")); err != nil {
+		t.Error("Could not write a file in an existing project")
+		t.Error(err)
+	}
+
+	r, err := p.ReadFile("notes/README.txt")
+	if err != nil {
+		t.Error("Could not read a file of an existing project")
+		t.Error(err)
+		return
+	}
+	defer r.Close()
+	b, _ := ioutil.ReadAll(r)
+	if string(b) != "gogns3 lab" {
+		t.Error("This project file seems to be wrong (content != gogns3 lab)")
+	}
+}
+
+func TestProjectReadFileError(t *testing.T) {
+	p := *resetTestProject(t)
+
+	if _, err := p.ReadFile("fakefakefake.txt"); err != nil {
+		switch e := err.(type) {
+		case *ServerError:
+			if e.Status != 404 {
+				t.Error(e)
+			}
+		default:
+			t.Error(e)
+		}
+	}
+}
+
+func TestProjectFileTimeout(t *testing.T) {
+	s, stop := newTestHTTPServer(func(w http.ResponseWriter, r *http.Request) {
+		// no answer within the timeout, once the file is received
+		ioutil.ReadAll(r.Body)
+		time.Sleep(200 * time.Millisecond)
+	})
+	defer stop()
+	s.APIVersion, s.Timeout = APIv2, 50*time.Millisecond
+	p := &Project{Server: s, UUID: "1"}
+
+	if _, err := p.ReadFile("README.txt"); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Reading a file must time out when the server does not answer (%v)", err)
+	}
+	if err := p.WriteFile("README.txt", strings.NewReader("gogns3 lab")); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Writing a file must time out when the server does not answer (%v)", err)
+	}
+}
+
+func TestProjectImport(t *testing.T) {
+	p := Project{
+		Name:   "gogns3",
+		Server: getTestServer(t),
+	}
+	if b, _ := p.Exists(); b {
+		p.Delete()
+	}
+
+	b := &bytes.Buffer{}
+	archive := zip.NewWriter(b)
+	w, _ := archive.Create("project.gns3")
+	w.Write([]byte(`{"name": "gogns3", "project_id": "", "revision": 9, "type": "topology", "version": "2.2.0",
+		"topology": {"computes": [], "drawings": [], "links": [], "nodes": []}}`))
+	archive.Close()
+
+	if err := p.Import(b); err != nil {
+		t.Error("Could not import a portable project")
+		t.Error(err)
+		return
+	}
+	if b, _ := p.Exists(); !b {
+		t.Error("The imported project must exist on the server")
+	}
+}
+
+func TestResourceConnectionError(t *testing.T) {
+	p := &Project{Name: "gogns3", Server: newTestUnreachableServer(), UUID: "1"}
+	n := &Node{Name: "PC1", NodeType: "vpcs", Project: p, UUID: "2"}
+	l := &Link{Project: p, UUID: "3"}
+
+	serverError := &ServerError{}
+	for _, err := range []error{p.Create(), p.Update(), p.Delete(), n.Create(), n.Update(), n.Delete(), l.Create(), l.Update(), l.Delete()} {
+		if err == nil || errors.As(err, &serverError) {
+			t.Errorf("A connection error must be returned as is (%v)", err)
+		}
+	}
+}
@@ -0,0 +1,118 @@
+package gogns3
+
+import (
+	"errors"
+	"math"
+	"math/rand"
+	"net"
+	"time"
+)
+
+// RetryPolicy describes how the requests failing with a transient error are
+// sent again. A request is retried:
+//   - for any method, when it could not be sent (connection refused) or when
+//     the server answered one of the RejectedStatuses, i.e. it did not process
+//     the request;
+//   - for the idempotent methods only, when the connection failed once the
+//     request was sent (e.g. connection reset) or when the server answered one
+//     of the RetryableStatuses.
+//
+// The delay before the nth retry is InitialBackoff * Multiplier^(n-1), capped
+// to MaxBackoff, and randomly spread by +/- Jitter (a fraction of the delay).
+type RetryPolicy struct {
+	IdempotentMethods []string
+	InitialBackoff    time.Duration
+	Jitter            float64
+	MaxAttempts       int
+	MaxBackoff        time.Duration
+	Multiplier        float64
+	RejectedStatuses  []int
+	RetryableStatuses []int
+}
+
+// DefaultRetryPolicy is a retry policy suitable for a loaded GNS3 server. The
+// GNS3 server answers 409 both for transient conflicts (e.g. a node busy
+// starting) and for permanent ones (e.g. a name already used or a port already
+// connected), which cannot be told apart: 409 is only retried for the
+// idempotent methods, a conflicting creation fails at once.
+var DefaultRetryPolicy = RetryPolicy{
+	IdempotentMethods: []string{"GET", "HEAD", "PUT", "DELETE", "OPTIONS"},
+	InitialBackoff:    200 * time.Millisecond,
+	Jitter:            0.2,
+	MaxAttempts:       5,
+	MaxBackoff:        5 * time.Second,
+	Multiplier:        2,
+	RetryableStatuses: []int{409, 500, 502, 503, 504},
+}
+
+// Logger is the interface used by a server to log its retries. It is
+// implemented by the standard log.Logger.
+type Logger interface {
+	Printf(format string, v ...interface{})
+}
+
+// retry tells whether a request must be sent again after the given attempt
+// (starting at 1) and after which delay. The status is 0 when the request
+// failed with an error. A nil policy never retries.
+func (p *RetryPolicy) retry(method string, attempt int, status int, err error) (time.Duration, bool) {
+	if p == nil || attempt >= p.MaxAttempts {
+		return 0, false
+	}
+
+	idempotent := containsString(p.IdempotentMethods, method)
+	switch {
+	case err != nil && isDialError(err):
+	case err != nil && idempotent:
+	case err == nil && containsInt(p.RejectedStatuses, status):
+	case err == nil && idempotent && containsInt(p.RetryableStatuses, status):
+	default:
+		return 0, false
+	}
+	return p.backoff(attempt), true
+}
+
+// backoff returns the delay before the retry following the given attempt
+func (p *RetryPolicy) backoff(attempt int) time.Duration {
+	multiplier := p.Multiplier
+	if multiplier < 1 {
+		multiplier = 1
+	}
+	d := float64(p.InitialBackoff) * math.Pow(multiplier, float64(attempt-1))
+	if p.MaxBackoff > 0 && d > float64(p.MaxBackoff) {
+		d = float64(p.MaxBackoff)
+	}
+	d *= 1 + p.Jitter*(2*rand.Float64()-1)
+	return time.Duration(d)
+}
+
+// isDialError checks an error occurred while connecting to the server, i.e.
+// before the request was sent
+func isDialError(err error) bool {
+	opError := &net.OpError{}
+	return errors.As(err, &opError) && opError.Op == "dial"
+}
+
+// logf logs a message if the server has a logger
+func (s *Server) logf(format string, v ...interface{}) {
+	if s.Logger != nil {
+		s.Logger.Printf(format, v...)
+	}
+}
+
+func containsString(list []string, s string) bool {
+	for _, e := range list {
+		if e == s {
+			return true
+		}
+	}
+	return false
+}
+
+func containsInt(list []int, i int) bool {
+	for _, e := range list {
+		if e == i {
+			return true
+		}
+	}
+	return false
+}
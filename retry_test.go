@@ -0,0 +1,117 @@
+package gogns3
+
+import (
+	"fmt"
+	"net"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+)
+
+// testLogger records the logged messages
+type testLogger []string
+
+func (l *testLogger) Printf(format string, v ...interface{}) {
+	*l = append(*l, fmt.Sprintf(format, v...))
+}
+
+func newTestRetryPolicy() *RetryPolicy {
+	policy := DefaultRetryPolicy
+	policy.InitialBackoff = time.Millisecond
+	policy.MaxAttempts = 3
+	return &policy
+}
+
+func TestServerRetry(t *testing.T) {
+	for _, c := range []struct {
+		method   string
+		statuses []int
+		attempts int
+		status   int
+	}{
+		{"PUT", []int{409, 409, 200}, 3, 200},
+		{"POST", []int{409, 201}, 1, 409},
+		{"POST", []int{502, 201}, 1, 502},
+		{"GET", []int{502, 503, 200}, 3, 200},
+		{"GET", []int{504, 504, 504, 200}, 3, 504},
+		{"DELETE", []int{404}, 1, 404},
+	} {
+		attempts := 0
+		s, stop := newTestHTTPServer(func(w http.ResponseWriter, r *http.Request) {
+			if r.URL.Path == "/v3/version" {
+				http.NotFound(w, r)
+				return
+			}
+			w.WriteHeader(c.statuses[attempts])
+			attempts++
+		})
+		logger := testLogger{}
+		s.RetryPolicy, s.Logger = newTestRetryPolicy(), &logger
+
+		status, _, err := s.HTTPRequest(c.method, s.url(), nil)
+		stop()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if status != c.status || attempts != c.attempts {
+			t.Errorf("%s %v: %d attempts and status %d, expected %d attempts and status %d", c.method, c.statuses, attempts, status, c.attempts, c.status)
+		}
+		if len(logger) != c.attempts-1 {
+			t.Errorf("%s %v: each retry must be logged (%v)", c.method, c.statuses, logger)
+		}
+	}
+}
+
+func TestServerRetryLog(t *testing.T) {
+	s, stop := newTestHTTPServer(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusConflict)
+	})
+	defer stop()
+	logger := testLogger{}
+	s.APIVersion, s.RetryPolicy, s.Logger = APIv2, newTestRetryPolicy(), &logger
+	s.RetryPolicy.Jitter = 0
+
+	s.HTTPRequest("PUT", s.url(), nil)
+	expected := "gogns3: PUT " + s.url() + " failed (status 409), retrying in 1ms (attempt 2/3)"
+	if len(logger) != 2 || logger[0] != expected || !strings.HasSuffix(logger[1], "retrying in 2ms (attempt 3/3)") {
+		t.Errorf("These retries seem to be mislogged:\n%s", strings.Join(logger, "\n"))
+	}
+}
+
+func TestServerRetryConnectionRefused(t *testing.T) {
+	// get a free port, nothing listens on it once closed
+	l, _ := net.Listen("tcp", "127.0.0.1:0")
+	port := l.Addr().(*net.TCPAddr).Port
+	l.Close()
+
+	logger := testLogger{}
+	s := &Server{APIVersion: APIv2, Host: "127.0.0.1", Port: port, RetryPolicy: newTestRetryPolicy(), Logger: &logger}
+	if _, _, err := s.HTTPRequest("POST", s.url(), nil); err == nil {
+		t.Fatal("This request must have failed")
+	}
+	if len(logger) != 2 {
+		t.Errorf("A request which could not be sent must be retried (%v)", logger)
+	}
+}
+
+func TestRetryPolicyBackoff(t *testing.T) {
+	p := RetryPolicy{InitialBackoff: 100 * time.Millisecond, MaxBackoff: time.Second, Multiplier: 2}
+	for attempt, expected := range []time.Duration{100, 200, 400, 800, 1000, 1000} {
+		if d := p.backoff(attempt + 1); d != expected*time.Millisecond {
+			t.Errorf("backoff(%d) = %v, expected %v", attempt+1, d, expected*time.Millisecond)
+		}
+	}
+
+	p.Jitter = 0.5
+	for i := 0; i < 100; i++ {
+		if d := p.backoff(1); d < 50*time.Millisecond || d > 150*time.Millisecond {
+			t.Fatalf("This backoff is out of the jitter range (%v)", d)
+		}
+	}
+
+	var none *RetryPolicy
+	if _, retry := none.retry("GET", 1, 503, nil); retry {
+		t.Error("A nil policy must never retry")
+	}
+}
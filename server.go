@@ -2,22 +2,120 @@ package gogns3
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"fmt"
+	"io"
 	"io/ioutil"
 	"net/http"
 	"strconv"
+	"sync"
+	"sync/atomic"
 	"time"
 )
 
-// Server is a basic structure describing a GNS3 server
+// Server is a basic structure describing a GNS3 server. The scheme defaults to
+// http. When a user is set, the requests are authenticated: with HTTP basic
+// authentication on a v2 server, with an access token on a v3 server (see
+// Login). The compute is used for the nodes created without one. The API
+// version is negotiated unless APIVersion is set. The requests failing with a
+// transient error are sent again following the retry policy, if set, and the
+// retries are logged with the logger, if set.
+//
+// The requests may be limited to RateLimit requests per second, with bursts of
+// RateBurst requests, and to MaxInFlight concurrent requests. A zero value
+// means no limit. These limits are read on the first request, they cannot be
+// changed afterwards. BulkParallelism is the number of concurrent operations
+// of the bulk operations of the projects, see DefaultBulkParallelism. Timeout
+// is the timeout of the requests, except the streams, see DefaultTimeout. The
+// file transfers are cancelled when no data flows for this timeout.
+//
+// A server can be used by several goroutines once its fields are set: all the
+// requests share one HTTP client and its pool of connections.
+// The objects of the server (projects, nodes, links, ...) are updated in place
+// with the answer of the server once their methods return: different objects
+// can be used concurrently, but one object must not be shared by goroutines
+// while one of its methods runs.
 type Server struct {
-	Host string
-	Port int
+	APIVersion      int
+	BulkParallelism int
+	ComputeID       string
+	Host            string
+	Logger          Logger
+	MaxInFlight     int
+	Password        string
+	Port            int
+	RateBurst       int
+	RateLimit       float64
+	RetryPolicy     *RetryPolicy
+	Scheme          string
+	Timeout         time.Duration
+	User            string
+
+	// mu protects the fields below, and the credentials changed by Login()
+	mu sync.Mutex
+	// loginMu serializes the logins, see login()
+	loginMu sync.Mutex
+	// negotiateMu serializes the negotiations of the API version, see API()
+	negotiateMu sync.Mutex
+	// HTTP client, see client()
+	httpClient *http.Client
+	// rate limiter and in-flight semaphore, see limits()
+	requestLimits *requestLimits
+
+	// negotiated API version, see API()
+	apiVersion int
+	// access token of a v3 server, see Login()
+	token       string
+	tokenExpiry time.Time
+	// version of the server, see Version()
+	version *Version
+}
+
+// url returns the URL of the projects of the server, or of the given path
+// under it
+func (s *Server) url(elements ...string) string {
+	return s.endpointURL(nil, append([]string{"projects"}, elements...)...)
+}
+
+// DefaultTimeout is the timeout of the requests when the server does not set
+// it
+const DefaultTimeout = 5 * time.Second
+
+// client returns the HTTP client of the server. It is created on the first
+// request, with its own transport so that the connections are pooled per
+// server.
+func (s *Server) client() *http.Client {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.httpClient == nil {
+		s.httpClient = &http.Client{Transport: http.DefaultTransport.(*http.Transport).Clone()}
+	}
+	return s.httpClient
 }
 
-func (s *Server) url() string {
-	return "http://" + s.Host + ":" + strconv.Itoa(s.Port) + "/v2/projects"
+// timeout returns the timeout of the requests
+func (s *Server) timeout() time.Duration {
+	if s.Timeout > 0 {
+		return s.Timeout
+	}
+	return DefaultTimeout
+}
+
+// credentials returns the user and password of the server
+func (s *Server) credentials() (string, string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.User, s.Password
+}
+
+// authenticate adds the credentials of the server to a request
+func (s *Server) authenticate(req *http.Request) {
+	if token := s.accessToken(); token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	} else if user, password := s.credentials(); user != "" {
+		req.SetBasicAuth(user, password)
+	}
 }
 
 // ServerError is the basic structure used for server related errors
@@ -34,18 +132,199 @@ func (e *ServerError) Error() string {
 }
 
 // HTTPRequest executes any HTTP request to the server.
-// Method, URL and body must be provided.
+// Method, URL and body must be provided. The request is sent again following
+// the retry policy of the server, if any. On a v3 server, the request is also
+// sent again after a new login if the access token has been rejected.
 func (s *Server) HTTPRequest(method string, url string, body []byte) (int, []byte, error) {
-	client := http.Client{Timeout: 5 * time.Second}
-	req, _ := http.NewRequest(method, url, bytes.NewReader(body))
+	for attempt := 1; ; attempt++ {
+		status, content, err := s.authenticatedRequest(method, url, body)
+		delay, retry := s.RetryPolicy.retry(method, attempt, status, err)
+		if !retry {
+			return status, content, err
+		}
+		s.logRetry(method, url, attempt, delay, status, err)
+		time.Sleep(delay)
+	}
+}
+
+func (s *Server) authenticatedRequest(method string, url string, body []byte) (int, []byte, error) {
+	if err := s.refreshToken(); err != nil {
+		return 0, nil, err
+	}
+	token := s.accessToken()
+	status, content, err := s.httpRequest(method, url, body)
+	if status == http.StatusUnauthorized && token != "" {
+		if err := s.relogin(token); err != nil {
+			return 0, nil, err
+		}
+		status, content, err = s.httpRequest(method, url, body)
+	}
+	return status, content, err
+}
+
+func (s *Server) httpRequest(method string, url string, body []byte) (int, []byte, error) {
+	release, err := s.acquire(context.Background())
+	if err != nil {
+		return 0, nil, err
+	}
+	defer release()
+
+	ctx, cancel := context.WithTimeout(context.Background(), s.timeout())
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, method, url, bytes.NewReader(body))
+	if err != nil {
+		return 0, nil, err
+	}
 	req.Header.Add("Content-Type", "application/json")
+	s.authenticate(req)
 
-	resp, _ := client.Do(req)
+	resp, err := s.client().Do(req)
+	if err != nil {
+		return 0, nil, err
+	}
 	content, err := ioutil.ReadAll(resp.Body)
 	defer resp.Body.Close()
 	return resp.StatusCode, content, err
 }
 
+// HTTPStream executes an HTTP request to the server and returns the response
+// body unread so that it can be consumed as a stream. No timeout is applied,
+// the context must be used to end the request. The caller must close the body.
+// As the request body cannot be sent twice, only the requests without a body
+// follow the retry policy of the server.
+func (s *Server) HTTPStream(ctx context.Context, method string, url string, body io.Reader) (io.ReadCloser, error) {
+	for attempt := 1; ; attempt++ {
+		stream, err := s.httpStream(ctx, method, url, body)
+		if err == nil || body != nil || ctx.Err() != nil {
+			return stream, err
+		}
+
+		status, cause := 0, err
+		if serverError, ok := err.(*ServerError); ok {
+			status, cause = serverError.Status, nil
+		}
+		delay, retry := s.RetryPolicy.retry(method, attempt, status, cause)
+		if !retry {
+			return nil, err
+		}
+		s.logRetry(method, url, attempt, delay, status, cause)
+		if err := sleepContext(ctx, delay); err != nil {
+			return nil, err
+		}
+	}
+}
+
+func (s *Server) httpStream(ctx context.Context, method string, url string, body io.Reader) (io.ReadCloser, error) {
+	if err := s.refreshToken(); err != nil {
+		return nil, err
+	}
+	req, err := http.NewRequestWithContext(ctx, method, url, body)
+	if err != nil {
+		return nil, err
+	}
+	s.authenticate(req)
+
+	// the request is in flight until the response is received, not while the
+	// response body is streamed
+	release, err := s.acquire(ctx)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := s.client().Do(req)
+	release()
+	if err != nil {
+		return nil, err
+	}
+	if !(resp.StatusCode >= 200 && resp.StatusCode < 300) {
+		defer resp.Body.Close()
+		content, _ := ioutil.ReadAll(resp.Body)
+		serverError := ServerError{Status: resp.StatusCode}
+		json.Unmarshal(content, &serverError)
+		return nil, &serverError
+	}
+	return resp.Body, nil
+}
+
+// fileStream executes a request transferring a file to or from the server.
+// Instead of a timeout of the whole request, the request is cancelled when no
+// data is sent or received within the timeout of the server, so that a large
+// file can be transferred. The caller must close the body.
+func (s *Server) fileStream(method string, url string, body io.Reader) (io.ReadCloser, error) {
+	ctx, cancel := context.WithCancel(context.Background())
+	idle := &idleTimer{cancel: cancel, timeout: s.timeout()}
+	idle.timer = time.AfterFunc(idle.timeout, idle.expire)
+	if body != nil {
+		body = &idleReader{Reader: body, idle: idle}
+	}
+	stream, err := s.HTTPStream(ctx, method, url, body)
+	if err != nil {
+		idle.stop()
+		return nil, idle.err(err)
+	}
+	return &idleReadCloser{idleReader{Reader: stream, idle: idle}, stream}, nil
+}
+
+// idleTimer cancels a request when it is not reset within a timeout
+type idleTimer struct {
+	cancel  func()
+	expired int32
+	timeout time.Duration
+	timer   *time.Timer
+}
+
+func (t *idleTimer) expire() {
+	atomic.StoreInt32(&t.expired, 1)
+	t.cancel()
+}
+
+func (t *idleTimer) stop() {
+	t.timer.Stop()
+	t.cancel()
+}
+
+// err returns the error of a request, replaced by a timeout error if the
+// request has been cancelled by the timer
+func (t *idleTimer) err(err error) error {
+	if err != nil && atomic.LoadInt32(&t.expired) == 1 {
+		return fmt.Errorf("no data transferred for %v: %w", t.timeout, context.DeadlineExceeded)
+	}
+	return err
+}
+
+// idleReader resets an idle timer on each read. It does not close the reader,
+// so that the HTTP client does not close the request body of the caller.
+type idleReader struct {
+	io.Reader
+	idle *idleTimer
+}
+
+func (r *idleReader) Read(p []byte) (int, error) {
+	n, err := r.Reader.Read(p)
+	r.idle.timer.Reset(r.idle.timeout)
+	return n, r.idle.err(err)
+}
+
+// idleReadCloser is an idleReader of a response body, which stops the idle
+// timer when the body is closed
+type idleReadCloser struct {
+	idleReader
+	closer io.Closer
+}
+
+func (r *idleReadCloser) Close() error {
+	r.idle.stop()
+	return r.closer.Close()
+}
+
+// logRetry logs a request which is going to be sent again
+func (s *Server) logRetry(method string, url string, attempt int, delay time.Duration, status int, err error) {
+	reason := "status " + strconv.Itoa(status)
+	if err != nil {
+		reason = err.Error()
+	}
+	s.logf("gogns3: %s %s failed (%s), retrying in %v (attempt %d/%d)", method, url, reason, delay, attempt+1, s.RetryPolicy.MaxAttempts)
+}
+
 // Test is a simple HTTP GET request to the server to check it is alive
 func (s *Server) Test() error {
 	_, _, err := s.HTTPRequest("GET", s.url(), nil)
@@ -55,7 +334,15 @@ func (s *Server) Test() error {
 // GetProjects gets the list of all projects on the server
 func (s *Server) GetProjects() ([]Project, error) {
 	// Send the HTTP request and analyze errors and status code
-	_, content, _ := s.HTTPRequest("GET", s.url(), nil)
+	status, content, err := s.HTTPRequest("GET", s.url(), nil)
+	if err != nil {
+		return nil, err
+	}
+	if !(status >= 200 && status < 300) {
+		serverError := ServerError{Status: status}
+		json.Unmarshal(content, &serverError)
+		return nil, &serverError
+	}
 
 	// Unmarshal the JSON-encoded project list
 	projects := []Project{}
@@ -1,26 +1,17 @@
 package gogns3
 
 import (
-	"os"
-	"strconv"
+	"net/http"
 	"testing"
 )
 
 func getTestServer(t *testing.T) *Server {
-	hostEnv, ok := os.LookupEnv("GNS3_HOST")
-	if !ok {
-		t.Error("GNS3_HOST environement variable is not set!")
+	s, err := LoadServer("")
+	if err != nil {
+		t.Error("Could not load the test server configuration")
+		t.Error(err)
 	}
-	portEnv, ok := os.LookupEnv("GNS3_PORT")
-	if !ok {
-		t.Error("GNS3_PORT environement variable is not set!")
-	}
-	p, _ := strconv.Atoi(portEnv)
-	s := Server{
-		Host: hostEnv,
-		Port: p,
-	}
-	return &s
+	return s
 }
 
 func TestServerOK(t *testing.T) {
@@ -69,3 +60,38 @@ func TestServerErrorString(t *testing.T) {
 		t.Error("Error string different than expected")
 	}
 }
+
+func TestServerAuthentication(t *testing.T) {
+	var user, password string
+	s, stop := newTestHTTPServer(func(w http.ResponseWriter, r *http.Request) {
+		user, password, _ = r.BasicAuth()
+		w.Write([]byte("[]"))
+	})
+	defer stop()
+	s.User, s.Password = "admin", "secret"
+
+	if _, err := s.GetProjects(); err != nil {
+		t.Fatal(err)
+	}
+	if user != "admin" || password != "secret" {
+		t.Errorf("The request must be authenticated (%s:%s)", user, password)
+	}
+}
+
+func TestServerGetVersion(t *testing.T) {
+	s := getTestServer(t)
+
+	v, err := s.Version()
+	if err != nil {
+		t.Error("Could not get the server version")
+		t.Error(err)
+		return
+	}
+	if compareVersions(v.Version, "2.0.0") < 0 {
+		t.Errorf("This server version seems to be wrong (%s)", v.Version)
+	}
+	if _, err := s.Capabilities(); err != nil {
+		t.Error("Could not get the server capabilities")
+		t.Error(err)
+	}
+}
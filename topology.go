@@ -0,0 +1,464 @@
+package gogns3
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	yaml "gopkg.in/yaml.v3"
+)
+
+// Topology is a declarative description of a project. Nodes are identified by
+// their name, which links use to reference their endpoints.
+type Topology struct {
+	Name     string            `json:"name"`
+	Nodes    []TopologyNode    `json:"nodes,omitempty"`
+	Links    []TopologyLink    `json:"links,omitempty"`
+	Drawings []TopologyDrawing `json:"drawings,omitempty"`
+}
+
+// TopologyNode is a node of a topology. The fields are ordered for the
+// readability of the topology files rather than alphabetically.
+type TopologyNode struct {
+	Name        string         `json:"name"`
+	NodeType    string         `json:"node_type"`
+	ComputeID   string         `json:"compute_id,omitempty"`
+	ConsoleType string         `json:"console_type,omitempty"`
+	Symbol      string         `json:"symbol,omitempty"`
+	X           int            `json:"x,omitempty"`
+	Y           int            `json:"y,omitempty"`
+	Z           int            `json:"z,omitempty"`
+	Properties  NodeProperties `json:"properties,omitempty"`
+}
+
+// TopologyLink is a link of a topology. Each endpoint is written as
+// "name:adapter/port", see ParseLinkEndpoint.
+type TopologyLink struct {
+	Endpoints []string     `json:"endpoints"`
+	LinkType  string       `json:"link_type,omitempty"`
+	Suspend   bool         `json:"suspend,omitempty"`
+	Filters   *LinkFilters `json:"filters,omitempty"`
+}
+
+// TopologyDrawing is a drawing of a topology, ordered like TopologyNode
+type TopologyDrawing struct {
+	X        int    `json:"x"`
+	Y        int    `json:"y"`
+	Z        int    `json:"z,omitempty"`
+	Rotation int    `json:"rotation,omitempty"`
+	Locked   bool   `json:"locked,omitempty"`
+	SVG      string `json:"svg"`
+}
+
+// LinkEndpoint is an end of a link identified by the node name
+type LinkEndpoint struct {
+	Node          string
+	AdapterNumber int
+	PortNumber    int
+}
+
+// TopologyError is returned when a topology is not valid
+type TopologyError struct {
+	Message string
+}
+
+func (e *TopologyError) Error() string {
+	return "Topology error: " + e.Message
+}
+
+type topologyNodeAlias TopologyNode
+
+// MarshalJSON copies the node type to the properties so that they are
+// filtered the same way as the properties of a Node.
+func (n TopologyNode) MarshalJSON() ([]byte, error) {
+	n.Properties.nodeType = n.NodeType
+	return json.Marshal(topologyNodeAlias(n))
+}
+
+// ParseLinkEndpoint parses a link endpoint written as "name:adapter/port"
+func ParseLinkEndpoint(s string) (LinkEndpoint, error) {
+	e := LinkEndpoint{}
+	idx := strings.LastIndex(s, ":")
+	if idx <= 0 {
+		return e, &TopologyError{Message: fmt.Sprintf("invalid link endpoint %q, expecting name:adapter/port", s)}
+	}
+	e.Node = s[:idx]
+	numbers := strings.Split(s[idx+1:], "/")
+	if len(numbers) != 2 {
+		return e, &TopologyError{Message: fmt.Sprintf("invalid link endpoint %q, expecting name:adapter/port", s)}
+	}
+	var err error
+	if e.AdapterNumber, err = strconv.Atoi(numbers[0]); err != nil || e.AdapterNumber < 0 {
+		return e, &TopologyError{Message: fmt.Sprintf("invalid adapter number in link endpoint %q", s)}
+	}
+	if e.PortNumber, err = strconv.Atoi(numbers[1]); err != nil || e.PortNumber < 0 {
+		return e, &TopologyError{Message: fmt.Sprintf("invalid port number in link endpoint %q", s)}
+	}
+	return e, nil
+}
+
+func (e LinkEndpoint) String() string {
+	return e.Node + ":" + strconv.Itoa(e.AdapterNumber) + "/" + strconv.Itoa(e.PortNumber)
+}
+
+// ParseTopology parses a topology written in YAML or JSON and validates it
+func ParseTopology(b []byte) (*Topology, error) {
+	var document interface{}
+	if err := yaml.Unmarshal(b, &document); err != nil {
+		return nil, err
+	}
+	// go through JSON so that the JSON tags apply to both formats
+	content, err := json.Marshal(yamlToJSONValue(document))
+	if err != nil {
+		return nil, err
+	}
+
+	t := &Topology{}
+	decoder := json.NewDecoder(bytes.NewReader(content))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(t); err != nil {
+		return nil, &TopologyError{Message: err.Error()}
+	}
+	// the properties are kept as written, they are checked here for the node
+	// types with a properties structure, see NodeProperties
+	for _, n := range t.Nodes {
+		switch n.NodeType {
+		case "ethernet_switch", "qemu", "vpcs":
+			b, _ := json.Marshal(n.Properties.raw)
+			decoder := json.NewDecoder(bytes.NewReader(b))
+			decoder.DisallowUnknownFields()
+			if err := decoder.Decode(&nodePropertiesAlias{}); err != nil {
+				return nil, &TopologyError{Message: fmt.Sprintf("node %q: %v", n.Name, err)}
+			}
+		}
+	}
+	return t, t.Validate()
+}
+
+// LoadTopologyFile reads and parses a YAML or JSON topology file
+func LoadTopologyFile(path string) (*Topology, error) {
+	b, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return ParseTopology(b)
+}
+
+// yamlToJSONValue converts the maps decoded by the YAML parser, which may have
+// any type of key, into maps with string keys
+func yamlToJSONValue(v interface{}) interface{} {
+	switch value := v.(type) {
+	case map[string]interface{}:
+		for key, item := range value {
+			value[key] = yamlToJSONValue(item)
+		}
+		return value
+	case map[interface{}]interface{}:
+		m := map[string]interface{}{}
+		for key, item := range value {
+			m[fmt.Sprint(key)] = yamlToJSONValue(item)
+		}
+		return m
+	case []interface{}:
+		for idx := range value {
+			value[idx] = yamlToJSONValue(value[idx])
+		}
+		return value
+	}
+	return v
+}
+
+// Validate checks the topology is consistent: node names are unique and links
+// have two endpoints on declared nodes
+func (t *Topology) Validate() error {
+	if t.Name == "" {
+		return &TopologyError{Message: "the topology must have a name"}
+	}
+	names := map[string]bool{}
+	for _, n := range t.Nodes {
+		if n.Name == "" {
+			return &TopologyError{Message: "all nodes must have a name"}
+		}
+		if names[n.Name] {
+			return &TopologyError{Message: fmt.Sprintf("node %q is declared more than once", n.Name)}
+		}
+		names[n.Name] = true
+	}
+	for _, l := range t.Links {
+		if len(l.Endpoints) != 2 {
+			return &TopologyError{Message: fmt.Sprintf("link %v must have two endpoints", l.Endpoints)}
+		}
+		for _, s := range l.Endpoints {
+			e, err := ParseLinkEndpoint(s)
+			if err != nil {
+				return err
+			}
+			if !names[e.Node] {
+				return &TopologyError{Message: fmt.Sprintf("link endpoint %q refers to an undeclared node", s)}
+			}
+		}
+	}
+	return nil
+}
+
+// node converts a topology node into a node of the project
+func (n *TopologyNode) node(p *Project) Node {
+	return Node{
+		ComputeID:   n.ComputeID,
+		ConsoleType: n.ConsoleType,
+		Name:        n.Name,
+		NodeType:    n.NodeType,
+		Project:     p,
+		Properties:  n.Properties,
+		Symbol:      n.Symbol,
+		X:           n.X,
+		Y:           n.Y,
+		Z:           n.Z,
+	}
+}
+
+// link converts a topology link into a link of the project, resolving the
+// node names into node UUIDs
+func (l *TopologyLink) link(p *Project, nodeIDs map[string]string) (Link, error) {
+	link := Link{
+		Filters:  l.Filters,
+		LinkType: l.LinkType,
+		Project:  p,
+		Suspend:  l.Suspend,
+	}
+	for _, s := range l.Endpoints {
+		e, err := ParseLinkEndpoint(s)
+		if err != nil {
+			return link, err
+		}
+		nodeID, ok := nodeIDs[e.Node]
+		if !ok {
+			return link, &TopologyError{Message: fmt.Sprintf("link endpoint %q refers to an unknown node", s)}
+		}
+		link.Nodes = append(link.Nodes, LinkNode{
+			AdapterNumber: e.AdapterNumber,
+			NodeID:        nodeID,
+			PortNumber:    e.PortNumber,
+		})
+	}
+	return link, nil
+}
+
+// drawing converts a topology drawing into a drawing of the project
+func (d *TopologyDrawing) drawing(p *Project) Drawing {
+	return Drawing{
+		Locked:   d.Locked,
+		Project:  p,
+		Rotation: d.Rotation,
+		SVG:      d.SVG,
+		X:        d.X,
+		Y:        d.Y,
+		Z:        d.Z,
+	}
+}
+
+// ExportTopology reads the nodes, links and drawings of the project and
+// returns them as a topology, with the node UUIDs replaced by the node names.
+// An AmbiguousError is returned if several nodes have the same name, as the
+// topology could not be read back.
+func (p *Project) ExportTopology() (*Topology, error) {
+	nodes, err := p.GetNodes()
+	if err != nil {
+		return nil, err
+	}
+	links, err := p.GetLinks()
+	if err != nil {
+		return nil, err
+	}
+	drawings, err := p.GetDrawings()
+	if err != nil {
+		return nil, err
+	}
+	return newTopology(p.Name, nodes, links, drawings)
+}
+
+// checkNodeNames returns an AmbiguousError if several nodes have the same
+// name, as the nodes of a topology are identified by their name
+func checkNodeNames(nodes []Node) error {
+	uuids := map[string][]string{}
+	for _, n := range nodes {
+		uuids[n.Name] = append(uuids[n.Name], n.UUID)
+	}
+	for _, n := range nodes {
+		if len(uuids[n.Name]) > 1 {
+			return &AmbiguousError{Kind: "node", Name: n.Name, UUIDs: uuids[n.Name]}
+		}
+	}
+	return nil
+}
+
+// newTopology converts the objects of a project into a topology. The nodes
+// and links are sorted so that the output is stable.
+func newTopology(name string, nodes []Node, links []Link, drawings []Drawing) (*Topology, error) {
+	t := &Topology{Name: name}
+
+	if err := checkNodeNames(nodes); err != nil {
+		return nil, err
+	}
+
+	names := map[string]string{}
+	for _, n := range nodes {
+		names[n.UUID] = n.Name
+		t.Nodes = append(t.Nodes, TopologyNode{
+			ComputeID:   n.ComputeID,
+			ConsoleType: n.ConsoleType,
+			Name:        n.Name,
+			NodeType:    n.NodeType,
+			Properties:  n.Properties,
+			Symbol:      n.Symbol,
+			X:           n.X,
+			Y:           n.Y,
+			Z:           n.Z,
+		})
+	}
+	sort.Slice(t.Nodes, func(i, j int) bool { return t.Nodes[i].Name < t.Nodes[j].Name })
+
+	for _, l := range links {
+		key, endpoints := linkKey(l, names)
+		if len(endpoints) != 2 || endpoints[0].Node == "" || endpoints[1].Node == "" {
+			continue
+		}
+		tl := TopologyLink{
+			Endpoints: strings.Split(key, " <-> "),
+			Suspend:   l.Suspend,
+		}
+		// Ethernet is the default link type
+		if l.LinkType != "ethernet" {
+			tl.LinkType = l.LinkType
+		}
+		if l.Filters != nil && *l.Filters != (LinkFilters{}) {
+			tl.Filters = l.Filters
+		}
+		t.Links = append(t.Links, tl)
+	}
+	sort.Slice(t.Links, func(i, j int) bool {
+		return strings.Join(t.Links[i].Endpoints, " ") < strings.Join(t.Links[j].Endpoints, " ")
+	})
+
+	for _, d := range drawings {
+		t.Drawings = append(t.Drawings, TopologyDrawing{
+			Locked:   d.Locked,
+			Rotation: d.Rotation,
+			SVG:      d.SVG,
+			X:        d.X,
+			Y:        d.Y,
+			Z:        d.Z,
+		})
+	}
+	return t, nil
+}
+
+// JSON returns the topology written in JSON
+func (t *Topology) JSON() ([]byte, error) {
+	return json.MarshalIndent(t, "", "  ")
+}
+
+// YAML returns the topology written in YAML. The fields are written in the
+// same order as in JSON and the empty properties are left out.
+func (t *Topology) YAML() ([]byte, error) {
+	content, err := json.Marshal(t)
+	if err != nil {
+		return nil, err
+	}
+	decoder := json.NewDecoder(bytes.NewReader(content))
+	decoder.UseNumber()
+	document, err := jsonToYAMLNode(decoder)
+	if err != nil {
+		return nil, err
+	}
+	b := &bytes.Buffer{}
+	encoder := yaml.NewEncoder(b)
+	encoder.SetIndent(2)
+	if err := encoder.Encode(document); err != nil {
+		return nil, err
+	}
+	return b.Bytes(), encoder.Close()
+}
+
+// WriteFile writes the topology in a file, in JSON if the file extension is
+// .json and in YAML otherwise
+func (t *Topology) WriteFile(path string) error {
+	var b []byte
+	var err error
+	if strings.ToLower(filepath.Ext(path)) == ".json" {
+		b, err = t.JSON()
+	} else {
+		b, err = t.YAML()
+	}
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(path, b, 0644)
+}
+
+// jsonToYAMLNode reads the next JSON value of the decoder and converts it into
+// a YAML node, keeping the order of the object keys. Null values and empty
+// objects are left out of the objects.
+func jsonToYAMLNode(decoder *json.Decoder) (*yaml.Node, error) {
+	token, err := decoder.Token()
+	if err != nil {
+		return nil, err
+	}
+
+	switch value := token.(type) {
+	case json.Delim:
+		switch value {
+		case '{':
+			node := &yaml.Node{Kind: yaml.MappingNode, Tag: "!!map"}
+			for decoder.More() {
+				key, err := decoder.Token()
+				if err != nil {
+					return nil, err
+				}
+				item, err := jsonToYAMLNode(decoder)
+				if err != nil {
+					return nil, err
+				}
+				if item.Tag == "!!null" || (item.Kind == yaml.MappingNode && len(item.Content) == 0) {
+					continue
+				}
+				node.Content = append(node.Content, &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: fmt.Sprint(key)}, item)
+			}
+			_, err := decoder.Token()
+			return node, err
+		case '[':
+			node := &yaml.Node{Kind: yaml.SequenceNode, Tag: "!!seq", Style: yaml.FlowStyle}
+			for decoder.More() {
+				item, err := jsonToYAMLNode(decoder)
+				if err != nil {
+					return nil, err
+				}
+				// only sequences of scalars are written on a single line
+				if item.Kind != yaml.ScalarNode {
+					node.Style = 0
+				}
+				node.Content = append(node.Content, item)
+			}
+			_, err := decoder.Token()
+			return node, err
+		}
+	case string:
+		return &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: value}, nil
+	case json.Number:
+		if _, err := value.Int64(); err == nil {
+			return &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!int", Value: value.String()}, nil
+		}
+		return &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!float", Value: value.String()}, nil
+	case bool:
+		return &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!bool", Value: strconv.FormatBool(value)}, nil
+	case nil:
+		return &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!null", Value: "null"}, nil
+	}
+	return nil, io.ErrUnexpectedEOF
+}
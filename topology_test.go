@@ -0,0 +1,265 @@
+package gogns3
+
+import (
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+)
+
+const testTopologyYAML = `
+name: gogns3
+nodes:
+  - name: SW1
+    node_type: ethernet_switch
+    compute_id: local
+  - name: PC1
+    node_type: vpcs
+    compute_id: local
+    x: -100
+  - name: PC2
+    node_type: vpcs
+    compute_id: local
+    x: 100
+links:
+  - endpoints: ["PC1:0/0", "SW1:0/1"]
+  - endpoints: ["PC2:0/0", "SW1:0/2"]
+    filters:
+      packet_loss: [10]
+drawings:
+  - svg: <svg height="50" width="100"><rect height="50" width="100" /></svg>
+    x: 0
+    y: 100
+`
+
+const testTopologyJSON = `{
+  "name": "gogns3",
+  "nodes": [
+    {"name": "VM1", "node_type": "qemu", "compute_id": "local", "properties": {"platform": "x86_64", "adapters": 4}}
+  ]
+}`
+
+func TestParseTopologyYAML(t *testing.T) {
+	topo, err := ParseTopology([]byte(testTopologyYAML))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if topo.Name != "gogns3" {
+		t.Error("This topology seems to be misparsed (name != gogns3)")
+	}
+	if len(topo.Nodes) != 3 || topo.Nodes[1].Name != "PC1" || topo.Nodes[1].X != -100 {
+		t.Error("This topology seems to be misparsed (nodes)")
+	}
+	if len(topo.Links) != 2 || topo.Links[1].Endpoints[1] != "SW1:0/2" {
+		t.Error("This topology seems to be misparsed (links)")
+	}
+	if f := topo.Links[1].Filters; f == nil || f.PacketLoss == nil || *f.PacketLoss != 10 {
+		t.Error("This topology seems to be misparsed (packet_loss != 10)")
+	}
+	if len(topo.Drawings) != 1 || topo.Drawings[0].Y != 100 {
+		t.Error("This topology seems to be misparsed (drawings)")
+	}
+}
+
+func TestParseTopologyJSON(t *testing.T) {
+	topo, err := ParseTopology([]byte(testTopologyJSON))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(topo.Nodes) != 1 {
+		t.Fatal("This topology seems to be misparsed (nodes)")
+	}
+	if topo.Nodes[0].Properties.Platform != "x86_64" || topo.Nodes[0].Properties.Adapters != 4 {
+		t.Error("This topology seems to be misparsed (properties)")
+	}
+}
+
+func TestParseTopologyError(t *testing.T) {
+	topologies := []string{
+		"nodes: []",
+		"name: lab\nnodes:\n  - name: R1\n    node_type: vpcs\n    foo: bar",
+		"name: lab\nnodes:\n  - name: R1\n    node_type: vpcs\n  - name: R1\n    node_type: vpcs",
+		"name: lab\nnodes:\n  - name: R1\n    node_type: vpcs\nlinks:\n  - endpoints: [R1:0/0, R2:0/0]",
+		"name: lab\nnodes:\n  - name: R1\n    node_type: vpcs\nlinks:\n  - endpoints: [R1:0/0]",
+		"name: lab\nnodes:\n  - name: R1\n    node_type: vpcs\n    properties:\n      foo: bar",
+	}
+
+	for _, s := range topologies {
+		_, err := ParseTopology([]byte(s))
+		if _, ok := err.(*TopologyError); !ok {
+			t.Errorf("This topology must be rejected with a TopologyError (%v):\n%s", err, s)
+		}
+	}
+}
+
+func TestParseLinkEndpoint(t *testing.T) {
+	e, err := ParseLinkEndpoint("R1:core:1/2")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if e.Node != "R1:core" || e.AdapterNumber != 1 || e.PortNumber != 2 {
+		t.Errorf("This endpoint seems to be misparsed (%+v)", e)
+	}
+	if e.String() != "R1:core:1/2" {
+		t.Errorf("This endpoint seems to be misformatted (%s)", e)
+	}
+
+	for _, s := range []string{"R1", ":0/0", "R1:0", "R1:a/0", "R1:0/-1"} {
+		if _, err := ParseLinkEndpoint(s); err == nil {
+			t.Errorf("This endpoint must be rejected (%s)", s)
+		}
+	}
+}
+
+func TestTopologyApply(t *testing.T) {
+	p := resetTestProject(t)
+	p.Delete()
+
+	topo, _ := ParseTopology([]byte(testTopologyYAML))
+	p, err := topo.Apply(getTestServer(t))
+	if err != nil {
+		t.Error("Could not apply a topology")
+		t.Error(err)
+		return
+	}
+
+	nodes, _ := p.GetNodes()
+	if len(nodes) != 3 {
+		t.Error("This project seems to be misconfigured (nodes != 3)")
+	}
+	links, _ := p.GetLinks()
+	if len(links) != 2 {
+		t.Error("This project seems to be misconfigured (links != 2)")
+	}
+	drawings, _ := p.GetDrawings()
+	if len(drawings) != 1 {
+		t.Error("This project seems to be misconfigured (drawings != 1)")
+	}
+}
+
+func TestTopologyExport(t *testing.T) {
+	loss := 10
+	nodes := []Node{
+		{Name: "SW1", NodeType: "ethernet_switch", ComputeID: "local", UUID: "1"},
+		{Name: "PC2", NodeType: "vpcs", ComputeID: "local", UUID: "3", X: 100},
+		{Name: "PC1", NodeType: "vpcs", ComputeID: "local", UUID: "2", X: -100},
+	}
+	links := []Link{
+		{LinkType: "ethernet", Nodes: []LinkNode{{NodeID: "1", AdapterNumber: 0, PortNumber: 2}, {NodeID: "3", AdapterNumber: 0, PortNumber: 0}}, Filters: &LinkFilters{PacketLoss: &loss}},
+		{LinkType: "ethernet", Nodes: []LinkNode{{NodeID: "2", AdapterNumber: 0, PortNumber: 0}, {NodeID: "1", AdapterNumber: 0, PortNumber: 1}}, Filters: &LinkFilters{}},
+	}
+	drawings := []Drawing{
+		{SVG: `<svg height="50" width="100"><rect height="50" width="100" /></svg>`, X: 0, Y: 100},
+	}
+
+	topo, err := newTopology("gogns3", nodes, links, drawings)
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, err := topo.YAML()
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := `name: gogns3
+nodes:
+  - name: PC1
+    node_type: vpcs
+    compute_id: local
+    x: -100
+  - name: PC2
+    node_type: vpcs
+    compute_id: local
+    x: 100
+  - name: SW1
+    node_type: ethernet_switch
+    compute_id: local
+links:
+  - endpoints: ['PC1:0/0', 'SW1:0/1']
+  - endpoints: ['PC2:0/0', 'SW1:0/2']
+    filters:
+      packet_loss: [10]
+drawings:
+  - x: 0
+    y: 100
+    svg: <svg height="50" width="100"><rect height="50" width="100" /></svg>
+`
+	if string(b) != expected {
+		t.Errorf("This topology seems to be misexported:\n%s", b)
+	}
+
+	// the exported topology must be the same as the original one
+	topo, err = ParseTopology(b)
+	if err != nil {
+		t.Fatal(err)
+	}
+	plan := &Plan{Project: &Project{}, Topology: topo, nodeIDs: map[string]string{}}
+	plan.diff(nodes, links, drawings)
+	if !plan.Empty() {
+		t.Errorf("This topology must match the project it was exported from:\n%s", plan)
+	}
+}
+
+func TestProjectExportTopology(t *testing.T) {
+	topo, _ := ParseTopology([]byte(testTopologyYAML))
+	p, err := topo.Apply(getTestServer(t))
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	exported, err := p.ExportTopology()
+	if err != nil {
+		t.Error("Could not export the topology of an existing project")
+		t.Error(err)
+		return
+	}
+	if len(exported.Nodes) != 3 || len(exported.Links) != 2 || len(exported.Drawings) != 1 {
+		t.Errorf("This topology seems to be misexported (%+v)", exported)
+	}
+}
+
+func TestTopologyExportRawProperties(t *testing.T) {
+	// a docker node has no properties structure, its properties are written
+	// out as read
+	n := Node{}
+	if err := json.Unmarshal([]byte(`{"name": "C1", "node_type": "docker", "node_id": "1", "properties": {"image": "alpine:latest", "adapters": 2}}`), &n); err != nil {
+		t.Fatal(err)
+	}
+	topo, err := newTopology("gogns3", []Node{n}, nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, err := topo.YAML()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(b), "image: alpine:latest") {
+		t.Errorf("The properties of the docker node must be exported:\n%s", b)
+	}
+
+	topo, err = ParseTopology(b)
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, _ = json.Marshal(topo.Nodes[0].node(nil))
+	if !strings.Contains(string(b), `"properties":{"adapters":2,"image":"alpine:latest"}`) {
+		t.Errorf("The properties of the docker node must be created:\n%s", b)
+	}
+	plan := &Plan{Project: &Project{}, Topology: topo, nodeIDs: map[string]string{}}
+	plan.diff([]Node{n}, nil, nil)
+	if !plan.Empty() {
+		t.Errorf("This topology must match the node it was exported from:\n%s", plan)
+	}
+}
+
+func TestTopologyExportDuplicateNames(t *testing.T) {
+	nodes := []Node{
+		{Name: "PC1", NodeType: "vpcs", UUID: "1"},
+		{Name: "PC1", NodeType: "vpcs", UUID: "2"},
+	}
+
+	ambiguousError := &AmbiguousError{}
+	if _, err := newTopology("gogns3", nodes, nil, nil); !errors.As(err, &ambiguousError) || ambiguousError.Name != "PC1" || len(ambiguousError.UUIDs) != 2 {
+		t.Errorf("Exporting duplicate node names must be ambiguous (%v)", err)
+	}
+}
@@ -0,0 +1,173 @@
+package gogns3
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Version is the version of a GNS3 server
+type Version struct {
+	Local   bool   `json:"local"`
+	Version string `json:"version"`
+}
+
+// Capabilities are the capabilities of a compute of a GNS3 server
+type Capabilities struct {
+	CPUs      int      `json:"cpus"`
+	DiskSize  int64    `json:"disk_size"`
+	Memory    int64    `json:"memory"`
+	NodeTypes []string `json:"node_types"`
+	Platform  string   `json:"platform"`
+	Version   string   `json:"version"`
+}
+
+// SupportsNodeType checks a node type can be created on the compute
+func (c *Capabilities) SupportsNodeType(nodeType string) bool {
+	for _, t := range c.NodeTypes {
+		if t == nodeType {
+			return true
+		}
+	}
+	return false
+}
+
+// Feature is a feature of the library which requires a minimum server version
+type Feature struct {
+	Name       string
+	MinVersion string
+}
+
+// Features requiring a server more recent than the first 2.x versions
+var (
+	FeatureLinkFilters   = Feature{Name: "link filters", MinVersion: "2.1.0"}
+	FeatureLinkSuspend   = Feature{Name: "link suspend", MinVersion: "2.2.0"}
+	FeatureCaptureStream = Feature{Name: "capture streaming", MinVersion: "2.2.0"}
+)
+
+// UnsupportedError is returned when a feature is not supported by the server
+type UnsupportedError struct {
+	Feature       Feature
+	ServerVersion string
+}
+
+func (e *UnsupportedError) Error() string {
+	return fmt.Sprintf("%s unsupported by server %s (requires %s)", e.Feature.Name, e.ServerVersion, e.Feature.MinVersion)
+}
+
+// Version gets the version of the server. The version is cached, it is only
+// requested once.
+func (s *Server) Version() (*Version, error) {
+	s.mu.Lock()
+	version := s.version
+	s.mu.Unlock()
+	if version != nil {
+		return version, nil
+	}
+
+	status, content, err := s.HTTPRequest("GET", s.endpointURL(nil, "version"), nil)
+	if err != nil {
+		return nil, err
+	}
+	if !(status >= 200 && status < 300) {
+		serverError := ServerError{Status: status}
+		json.Unmarshal(content, &serverError)
+		return nil, &serverError
+	}
+
+	v := &Version{}
+	if err := json.Unmarshal(content, v); err != nil {
+		return nil, err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.version = v
+	return v, nil
+}
+
+// Capabilities gets the capabilities of the default compute of the server, or
+// of the local compute if the server has no default compute
+func (s *Server) Capabilities() (*Capabilities, error) {
+	computeID := s.ComputeID
+	if computeID == "" {
+		computeID = "local"
+	}
+	status, content, err := s.HTTPRequest("GET", s.endpointURL(nil, "computes", computeID), nil)
+	if err != nil {
+		return nil, err
+	}
+	if !(status >= 200 && status < 300) {
+		serverError := ServerError{Status: status}
+		json.Unmarshal(content, &serverError)
+		return nil, &serverError
+	}
+
+	compute := struct {
+		Capabilities Capabilities `json:"capabilities"`
+	}{}
+	if err := json.Unmarshal(content, &compute); err != nil {
+		return nil, err
+	}
+	return &compute.Capabilities, nil
+}
+
+// Supports checks the server supports a feature
+func (s *Server) Supports(f Feature) (bool, error) {
+	v, err := s.Version()
+	if err != nil {
+		return false, err
+	}
+	return compareVersions(v.Version, f.MinVersion) >= 0, nil
+}
+
+// require returns an UnsupportedError if the server does not support a feature
+func (s *Server) require(f Feature) error {
+	v, err := s.Version()
+	if err != nil {
+		return err
+	}
+	if compareVersions(v.Version, f.MinVersion) < 0 {
+		return &UnsupportedError{Feature: f, ServerVersion: v.Version}
+	}
+	return nil
+}
+
+// compareVersions compares two versions, e.g. 2.2.17 and 2.2.0rc1, on their
+// numeric parts only. It returns -1, 0 or 1.
+func compareVersions(a string, b string) int {
+	va, vb := versionNumbers(a), versionNumbers(b)
+	for len(va) < len(vb) {
+		va = append(va, 0)
+	}
+	for len(vb) < len(va) {
+		vb = append(vb, 0)
+	}
+	for idx := range va {
+		if va[idx] < vb[idx] {
+			return -1
+		}
+		if va[idx] > vb[idx] {
+			return 1
+		}
+	}
+	return 0
+}
+
+// versionNumbers returns the numbers of a version, ignoring the pre-release
+// suffixes
+func versionNumbers(v string) []int {
+	numbers := []int{}
+	for _, element := range strings.Split(v, ".") {
+		end := 0
+		for end < len(element) && element[end] >= '0' && element[end] <= '9' {
+			end++
+		}
+		n, _ := strconv.Atoi(element[:end])
+		numbers = append(numbers, n)
+		if end < len(element) {
+			break
+		}
+	}
+	return numbers
+}
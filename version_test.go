@@ -0,0 +1,113 @@
+package gogns3
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strconv"
+	"testing"
+)
+
+// newTestHTTPServer starts an HTTP server answering with the handler and
+// returns the matching Server
+func newTestHTTPServer(handler http.HandlerFunc) (*Server, func()) {
+	ts := httptest.NewServer(handler)
+	u, _ := url.Parse(ts.URL)
+	port, _ := strconv.Atoi(u.Port())
+	return &Server{Host: u.Hostname(), Port: port}, ts.Close
+}
+
+// newTestUnreachableServer returns a v2 server which refuses the connections
+func newTestUnreachableServer() *Server {
+	s, stop := newTestHTTPServer(http.NotFound)
+	stop()
+	s.APIVersion = APIv2
+	return s
+}
+
+func TestServerVersion(t *testing.T) {
+	requests := 0
+	s, stop := newTestHTTPServer(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		switch r.URL.Path {
+		case "/v2/version":
+			w.Write([]byte(`{"local": true, "version": "2.2.17"}`))
+		case "/v2/computes/local":
+			w.Write([]byte(`{"compute_id": "local", "capabilities": {"node_types": ["vpcs", "qemu"], "platform": "linux", "version": "2.2.17"}}`))
+		default:
+			http.NotFound(w, r)
+		}
+	})
+	defer stop()
+
+	v, err := s.Version()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if v.Version != "2.2.17" || !v.Local {
+		t.Errorf("This version seems to be misread (%+v)", v)
+	}
+	sent := requests
+	s.Version()
+	if requests != sent {
+		t.Errorf("The version must be cached (requests = %d)", requests)
+	}
+
+	c, err := s.Capabilities()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if c.Platform != "linux" || !c.SupportsNodeType("vpcs") || c.SupportsNodeType("iou") {
+		t.Errorf("These capabilities seem to be misread (%+v)", c)
+	}
+
+	if ok, _ := s.Supports(FeatureLinkSuspend); !ok {
+		t.Error("This server must support link suspend")
+	}
+}
+
+func TestServerUnsupported(t *testing.T) {
+	s, stop := newTestHTTPServer(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/v3/version" {
+			http.NotFound(w, r)
+			return
+		}
+		if r.URL.Path != "/v2/version" {
+			t.Errorf("No request must be sent for an unsupported feature (%s %s)", r.Method, r.URL.Path)
+		}
+		w.Write([]byte(`{"local": false, "version": "2.1.21"}`))
+	})
+	defer stop()
+
+	l := Link{Project: &Project{Server: s, UUID: "1"}, UUID: "a"}
+	err := l.SuspendLink()
+	var unsupported *UnsupportedError
+	if !errors.As(err, &unsupported) || unsupported.Feature != FeatureLinkSuspend {
+		t.Fatalf("Link suspend must be unsupported by server 2.1 (%v)", err)
+	}
+	if err.Error() != "link suspend unsupported by server 2.1.21 (requires 2.2.0)" {
+		t.Errorf("This error seems to be misformatted (%s)", err)
+	}
+	if ok, _ := s.Supports(FeatureLinkFilters); !ok {
+		t.Error("This server must support link filters")
+	}
+}
+
+func TestCompareVersions(t *testing.T) {
+	for _, c := range []struct {
+		a, b     string
+		expected int
+	}{
+		{"2.2.17", "2.2.0", 1},
+		{"2.1.21", "2.2.0", -1},
+		{"2.2", "2.2.0", 0},
+		{"2.2.0rc1", "2.2.0", 0},
+		{"3.0.0a1", "2.2.0", 1},
+		{"2.10.0", "2.9.0", 1},
+	} {
+		if r := compareVersions(c.a, c.b); r != c.expected {
+			t.Errorf("compareVersions(%s, %s) = %d, expected %d", c.a, c.b, r, c.expected)
+		}
+	}
+}